@@ -0,0 +1,198 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/coreos/go-systemd/v22/sdjournal"
+)
+
+// ValidOutputs returns the output formats for the log entries, the names
+// are the same as the ones of journalctl
+func ValidOutputs() []string {
+	return []string{"json", "short", "short-iso", "cat", "export", "verbose"}
+}
+
+// ValidFields returns the commonly requested fields, any other field name
+// of the journal and the keyword 'all' are accepted as well
+func ValidFields() []string {
+	return []string{"PRIORITY", "_PID", "_COMM", "CODE_FILE", "CODE_LINE", "CODE_FUNC", "_BOOT_ID", "INVOCATION_ID", "_SYSTEMD_UNIT", "SYSLOG_IDENTIFIER", "_EXE", "_CMDLINE", "_UID"}
+}
+
+// fieldAliases are looked up if the requested field isn't part of the entry,
+// e.g. messages of a service carry _SYSTEMD_INVOCATION_ID, while the ones
+// from systemd about the service carry INVOCATION_ID
+var fieldAliases = map[string]string{
+	"INVOCATION_ID":          "_SYSTEMD_INVOCATION_ID",
+	"_SYSTEMD_INVOCATION_ID": "INVOCATION_ID",
+	"_PID":                   "SYSLOG_PID",
+}
+
+type jsonEntry struct {
+	Time   time.Time      `json:"time"`
+	Unit   string         `json:"unit"`
+	Host   string         `json:"host"`
+	Msg    any            `json:"message"`
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// isBinary checks if the value can't be printed as text, which is the case
+// for non UTF-8 values and values with control characters
+func isBinary(val string) bool {
+	if !utf8.ValidString(val) {
+		return true
+	}
+	for _, r := range val {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonValue returns the value as string, or like journalctl as an array of
+// bytes if the value is binary
+func jsonValue(val string) any {
+	if !isBinary(val) {
+		return val
+	}
+	bytes := make([]int, len(val))
+	for i := 0; i < len(val); i++ {
+		bytes[i] = int(val[i])
+	}
+	return bytes
+}
+
+// textValue returns the value or a placeholder if the value is binary
+func textValue(val string) string {
+	if isBinary(val) {
+		return fmt.Sprintf("[%d bytes blob data]", len(val))
+	}
+	return val
+}
+
+// getField gets the field of the entry, also looking up its alias
+func getField(entry *sdjournal.JournalEntry, field string) (string, bool) {
+	if val, ok := entry.Fields[field]; ok {
+		return val, true
+	}
+	if alias, ok := fieldAliases[field]; ok {
+		val, ok := entry.Fields[alias]
+		return val, ok
+	}
+	return "", false
+}
+
+// selectFields returns the requested fields of the entry in sorted order,
+// with 'all' or no request every field of the entry is returned
+func selectFields(entry *sdjournal.JournalEntry, fields []string, all bool) (keys []string, values map[string]string) {
+	values = make(map[string]string)
+	if all || slices.Contains(fields, "all") {
+		for key, val := range entry.Fields {
+			values[key] = val
+		}
+	} else {
+		for _, field := range fields {
+			if val, ok := getField(entry, field); ok {
+				values[field] = val
+			}
+		}
+	}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, values
+}
+
+// normalizeFields uppercases the field names and removes duplicates
+func normalizeFields(fields []string) (ret []string) {
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if !strings.EqualFold(field, "all") {
+			field = strings.ToUpper(field)
+		} else {
+			field = "all"
+		}
+		if field != "" && !slices.Contains(ret, field) {
+			ret = append(ret, field)
+		}
+	}
+	return ret
+}
+
+// identifier returns the syslog like identifier with pid as used by the
+// short formats
+func identifier(entry *sdjournal.JournalEntry) string {
+	ident := entry.Fields["SYSLOG_IDENTIFIER"]
+	if ident == "" {
+		ident = entry.Fields["_COMM"]
+	}
+	if pid, ok := getField(entry, "_PID"); ok && pid != "" {
+		return fmt.Sprintf("%s[%s]", ident, pid)
+	}
+	return ident
+}
+
+// formatEntry formats an entry in the given output format, the fields are
+// only used by the json, export and verbose formats
+func formatEntry(entry *sdjournal.JournalEntry, output string, fields []string) (string, error) {
+	timestamp := time.Unix(0, int64(entry.RealtimeTimestamp)*int64(time.Microsecond))
+	switch output {
+	case "", "json":
+		structEntr := jsonEntry{
+			Unit: entry.Fields["SYSLOG_IDENTIFIER"],
+			Time: timestamp,
+			Host: entry.Fields["_HOSTNAME"],
+			Msg:  jsonValue(entry.Fields["MESSAGE"]),
+		}
+		if structEntr.Unit == "" {
+			structEntr.Unit = fmt.Sprintf("%s:%s", entry.Fields["_SYSTEMD_UNIT"], entry.Fields["_SYSTEMD_USER_UNIT"])
+		}
+		if len(fields) > 0 {
+			_, values := selectFields(entry, fields, false)
+			structEntr.Fields = make(map[string]any)
+			for key, val := range values {
+				structEntr.Fields[key] = jsonValue(val)
+			}
+		}
+		jsonByte, err := json.Marshal(&structEntr)
+		if err != nil {
+			return "", err
+		}
+		return string(jsonByte), nil
+	case "short":
+		return fmt.Sprintf("%s %s %s: %s", timestamp.Format(time.Stamp),
+			entry.Fields["_HOSTNAME"], identifier(entry), textValue(entry.Fields["MESSAGE"])), nil
+	case "short-iso":
+		return fmt.Sprintf("%s %s %s: %s", timestamp.Format("2006-01-02T15:04:05-0700"),
+			entry.Fields["_HOSTNAME"], identifier(entry), textValue(entry.Fields["MESSAGE"])), nil
+	case "cat":
+		return textValue(entry.Fields["MESSAGE"]), nil
+	case "export":
+		var bld strings.Builder
+		fmt.Fprintf(&bld, "__CURSOR=%s\n__REALTIME_TIMESTAMP=%d\n__MONOTONIC_TIMESTAMP=%d\n",
+			entry.Cursor, entry.RealtimeTimestamp, entry.MonotonicTimestamp)
+		keys, values := selectFields(entry, fields, len(fields) == 0)
+		for _, key := range keys {
+			// multi line values would break the line based format
+			fmt.Fprintf(&bld, "%s=%s\n", key, strings.ReplaceAll(textValue(values[key]), "\n", "\\n"))
+		}
+		return strings.TrimSuffix(bld.String(), "\n"), nil
+	case "verbose":
+		var bld strings.Builder
+		fmt.Fprintf(&bld, "%s [%s]\n", timestamp.Format("Mon 2006-01-02 15:04:05.000000 MST"), entry.Cursor)
+		keys, values := selectFields(entry, fields, len(fields) == 0)
+		for _, key := range keys {
+			fmt.Fprintf(&bld, "    %s=%s\n", key, textValue(values[key]))
+		}
+		return strings.TrimSuffix(bld.String(), "\n"), nil
+	}
+	return "", fmt.Errorf("invalid output format %s, valid formats are: %v", output, ValidOutputs())
+}
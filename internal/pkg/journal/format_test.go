@@ -0,0 +1,89 @@
+package journal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/sdjournal"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEntry() *sdjournal.JournalEntry {
+	return &sdjournal.JournalEntry{
+		Fields: map[string]string{
+			"MESSAGE":                "Started foo.",
+			"SYSLOG_IDENTIFIER":      "foo",
+			"_HOSTNAME":              "host",
+			"_PID":                   "42",
+			"PRIORITY":               "6",
+			"_SYSTEMD_INVOCATION_ID": "abc",
+		},
+		Cursor:             "s=1",
+		RealtimeTimestamp:  0,
+		MonotonicTimestamp: 10,
+	}
+}
+
+func TestFormatEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   func() *sdjournal.JournalEntry
+		output  string
+		fields  []string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "cat",
+			entry:  testEntry,
+			output: "cat",
+			want:   "Started foo.",
+		},
+		{
+			name: "cat binary message",
+			entry: func() *sdjournal.JournalEntry {
+				e := testEntry()
+				e.Fields["MESSAGE"] = "\xff\x00ab"
+				return e
+			},
+			output: "cat",
+			want:   "[4 bytes blob data]",
+		},
+		{
+			name:   "export selected fields with alias",
+			entry:  testEntry,
+			output: "export",
+			fields: []string{"PRIORITY", "INVOCATION_ID"},
+			want:   "__CURSOR=s=1\n__REALTIME_TIMESTAMP=0\n__MONOTONIC_TIMESTAMP=10\nINVOCATION_ID=abc\nPRIORITY=6",
+		},
+		{
+			name:    "invalid output",
+			entry:   testEntry,
+			output:  "foo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatEntry(tt.entry(), tt.output, tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("formatEntry() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatEntryJSON(t *testing.T) {
+	entry := testEntry()
+	entry.Fields["CODE_FILE"] = "a\x01b"
+	got, err := formatEntry(entry, "json", normalizeFields([]string{"priority", "code_file", "_pid", "priority"}))
+	assert.NoError(t, err)
+	var res map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(got), &res))
+	// time depends on the local timezone
+	delete(res, "time")
+	gotJson, _ := json.Marshal(res)
+	assert.JSONEq(t, `{"unit":"foo","host":"host","message":"Started foo.","fields":{"PRIORITY":"6","CODE_FILE":[97,1,98],"_PID":"42"}}`, string(gotJson))
+}
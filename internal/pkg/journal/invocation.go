@@ -0,0 +1,391 @@
+package journal
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/sdjournal"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/systemd"
+)
+
+// UnitProperties is used to get the properties of a unit, like the
+// InvocationID of its latest run
+type UnitProperties interface {
+	GetAllPropertiesContext(ctx context.Context, unitName string) (map[string]interface{}, error)
+}
+
+// message ids of systemd, see sd-messages.h
+const (
+	messageUnitStarted       = "39f53479d3a045ac8e11786248231fbf"
+	messageUnitStopped       = "9d1aaa27d60140bd96365438aad20286"
+	messageUnitSuccess       = "7ad2d189f7e94e70a38c781354912448"
+	messageUnitFailureResult = "d9b373ed55a64feb8242e02dbe79a49c"
+	messageUnitProcessExit   = "98e322203f7a4ed290d09fe03c09fe15"
+	messageCoredump          = "fc2e22bc6ee647b6b90729ab34a250b1"
+)
+
+// priorityErr is the syslog priority err
+const priorityErr = 3
+
+// InvocationSummary describes a single run of a unit
+type InvocationSummary struct {
+	InvocationID string    `json:"invocation_id"`
+	Unit         string    `json:"unit,omitempty"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Entries      int       `json:"entries"`
+	Finished     bool      `json:"finished"`
+	ExitCode     string    `json:"exit_code,omitempty"`
+	ExitStatus   string    `json:"exit_status,omitempty"`
+	Result       string    `json:"result,omitempty"`
+}
+
+// normalizeInvocationID returns the id in the format used by the journal,
+// the id may also be given in the UUID format
+func normalizeInvocationID(id string) (string, error) {
+	id = strings.ToLower(strings.ReplaceAll(id, "-", ""))
+	if len(id) != 32 {
+		return "", fmt.Errorf("invalid invocation id: %s", id)
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", fmt.Errorf("invalid invocation id %s: %w", id, err)
+	}
+	return id, nil
+}
+
+// invocationOfEntry returns the invocation id of the entry, which is
+// _SYSTEMD_INVOCATION_ID for messages of the service itself and
+// INVOCATION_ID or USER_INVOCATION_ID for messages of the manager
+func invocationOfEntry(entry *sdjournal.JournalEntry) string {
+	for _, field := range []string{"_SYSTEMD_INVOCATION_ID", "INVOCATION_ID", "USER_INVOCATION_ID"} {
+		if id := entry.Fields[field]; id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// latestInvocation returns the InvocationID property of the unit, if the
+// properties can be accessed
+func (sj *HostLog) latestInvocation(ctx context.Context, unit string) (props map[string]interface{}, id string) {
+	if sj.units == nil {
+		return nil, ""
+	}
+	props, err := sj.units.GetAllPropertiesContext(ctx, unit)
+	if err != nil {
+		return nil, ""
+	}
+	if idBytes, ok := props["InvocationID"].([]byte); ok && len(idBytes) > 0 {
+		id = hex.EncodeToString(idBytes)
+	}
+	return props, id
+}
+
+// resolveInvocation resolves 'latest' and 'previous' to an invocation id of
+// the unit. The latest id is taken from the properties of the unit, if
+// these aren't available or for the previous run, the journal is searched
+// backwards for the invocation ids of the unit.
+func (sj *HostLog) resolveInvocation(ctx context.Context, unit, invocation string) (string, map[string]interface{}, error) {
+	if invocation != "latest" && invocation != "previous" {
+		id, err := normalizeInvocationID(invocation)
+		return id, nil, err
+	}
+	if unit == "" {
+		return "", nil, fmt.Errorf("the unit is needed to resolve the %s invocation", invocation)
+	}
+	props, latest := sj.latestInvocation(ctx, unit)
+	if invocation == "latest" && latest != "" {
+		return latest, props, nil
+	}
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	sj.journal.FlushMatches()
+	defer sj.journal.FlushMatches()
+	for i, match := range []string{"_SYSTEMD_UNIT=", "UNIT=", "_SYSTEMD_USER_UNIT=", "USER_UNIT="} {
+		if i > 0 {
+			if err := sj.journal.AddDisjunction(); err != nil {
+				return "", nil, fmt.Errorf("failed to add unit filter: %w", err)
+			}
+		}
+		if err := sj.journal.AddMatch(match + unit); err != nil {
+			return "", nil, fmt.Errorf("failed to add unit filter: %w", err)
+		}
+	}
+	if err := sj.journal.SeekTail(); err != nil {
+		return "", nil, fmt.Errorf("failed to seek to end: %w", err)
+	}
+	found := []string{}
+	if latest != "" {
+		found = append(found, latest)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", nil, err
+		}
+		ret, err := sj.journal.Previous()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read previous entry: %w", err)
+		}
+		if ret == 0 {
+			break
+		}
+		entry, err := sj.journal.GetEntry()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to get entry: %w", err)
+		}
+		id := invocationOfEntry(entry)
+		if id == "" || (len(found) > 0 && found[len(found)-1] == id) {
+			continue
+		}
+		found = append(found, id)
+		if invocation == "latest" && len(found) == 1 {
+			return id, nil, nil
+		}
+		if len(found) == 2 {
+			return id, nil, nil
+		}
+	}
+	return "", nil, fmt.Errorf("couldn't find the %s invocation of %s in the journal", invocation, unit)
+}
+
+// updateSummary updates the summary with start, end and exit status of the
+// run contained in the entry
+func updateSummary(summary *InvocationSummary, entry *sdjournal.JournalEntry) {
+	timestamp := time.Unix(0, int64(entry.RealtimeTimestamp)*int64(time.Microsecond))
+	if summary.Entries == 0 {
+		summary.Start = timestamp
+	}
+	summary.End = timestamp
+	summary.Entries++
+	if summary.Unit == "" {
+		for _, field := range []string{"UNIT", "USER_UNIT", "_SYSTEMD_UNIT", "_SYSTEMD_USER_UNIT"} {
+			if unit := entry.Fields[field]; unit != "" {
+				summary.Unit = unit
+				break
+			}
+		}
+	}
+	switch entry.Fields["MESSAGE_ID"] {
+	case messageUnitStarted:
+		summary.Start = timestamp
+	case messageUnitProcessExit:
+		summary.ExitCode = entry.Fields["EXIT_CODE"]
+		summary.ExitStatus = entry.Fields["EXIT_STATUS"]
+	case messageUnitFailureResult:
+		summary.Result = entry.Fields["UNIT_RESULT"]
+		summary.Finished = true
+	case messageUnitSuccess, messageUnitStopped:
+		if summary.Result == "" {
+			summary.Result = "success"
+		}
+		summary.Finished = true
+	}
+}
+
+// summaryFromProperties completes the summary with the properties of the
+// unit, which describe the latest run
+func summaryFromProperties(summary *InvocationSummary, props map[string]interface{}) {
+	if state, ok := props["ActiveState"].(string); ok && (state == "inactive" || state == "failed") {
+		summary.Finished = true
+	}
+	if !summary.Finished {
+		return
+	}
+	if result, ok := props["Result"].(string); ok && summary.Result == "" {
+		summary.Result = result
+	}
+	if code, ok := props["ExecMainCode"].(int32); ok && summary.ExitCode == "" {
+		summary.ExitCode = systemd.ExitCodeName(code)
+		if status, ok := props["ExecMainStatus"].(int32); ok && summary.ExitCode != "" {
+			summary.ExitStatus = fmt.Sprintf("%d", status)
+		}
+	}
+}
+
+// walkInvocation calls fn for every entry of the invocation, starting with
+// the oldest one. The journal is locked while walking, so fn must not use
+// the journal.
+func (sj *HostLog) walkInvocation(ctx context.Context, id string, fn func(entry *sdjournal.JournalEntry) error) error {
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	sj.journal.FlushMatches()
+	defer sj.journal.FlushMatches()
+	for i, match := range []string{"_SYSTEMD_INVOCATION_ID=", "INVOCATION_ID=", "USER_INVOCATION_ID="} {
+		if i > 0 {
+			if err := sj.journal.AddDisjunction(); err != nil {
+				return fmt.Errorf("failed to add invocation filter: %w", err)
+			}
+		}
+		if err := sj.journal.AddMatch(match + id); err != nil {
+			return fmt.Errorf("failed to add invocation filter: %w", err)
+		}
+	}
+	if err := sj.journal.SeekHead(); err != nil {
+		return fmt.Errorf("failed to seek to start: %w", err)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ret, err := sj.journal.Next()
+		if err != nil {
+			return fmt.Errorf("failed to read next entry: %w", err)
+		}
+		if ret == 0 {
+			return nil
+		}
+		entry, err := sj.journal.GetEntry()
+		if err != nil {
+			return fmt.Errorf("failed to get entry: %w", err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// invocationLines returns the last count entries of the invocation of the
+// unit for which keep returns true in the given output format
+func (sj *HostLog) invocationLines(ctx context.Context, unit, invocation string, count int, output string, keep func(entry *sdjournal.JournalEntry) bool) ([]string, error) {
+	id, _, err := sj.resolveInvocation(ctx, unit, invocation)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	err = sj.walkInvocation(ctx, id, func(entry *sdjournal.JournalEntry) error {
+		if !keep(entry) {
+			return nil
+		}
+		text, err := formatEntry(entry, output, nil)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, text)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 && len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return lines, nil
+}
+
+// InvocationErrors returns the last count entries of the invocation of the
+// unit with the priority err or higher in the short-iso format
+func (sj *HostLog) InvocationErrors(ctx context.Context, unit, invocation string, count int) ([]string, error) {
+	return sj.invocationLines(ctx, unit, invocation, count, "short-iso", func(entry *sdjournal.JournalEntry) bool {
+		prio, err := strconv.Atoi(entry.Fields["PRIORITY"])
+		return err == nil && prio <= priorityErr
+	})
+}
+
+// InvocationOutput returns the last count messages which the processes of
+// the invocation wrote, without the messages of the manager
+func (sj *HostLog) InvocationOutput(ctx context.Context, unit, invocation string, count int) ([]string, error) {
+	return sj.invocationLines(ctx, unit, invocation, count, "cat", func(entry *sdjournal.JournalEntry) bool {
+		return entry.Fields["_SYSTEMD_INVOCATION_ID"] != ""
+	})
+}
+
+// listInvocation lists all entries of a single run of an unit, the last
+// content contains the summary of the run
+func (sj *HostLog) listInvocation(ctx context.Context, params *ListLogParams, fields []string) (*mcp.CallToolResult, any, error) {
+	id, props, err := sj.resolveInvocation(ctx, params.Unit, params.Invocation)
+	if err != nil {
+		return nil, nil, err
+	}
+	summary := InvocationSummary{InvocationID: id}
+	txtContentList := []mcp.Content{}
+	err = sj.walkInvocation(ctx, id, func(entry *sdjournal.JournalEntry) error {
+		updateSummary(&summary, entry)
+		text, err := formatEntry(entry, params.Output, fields)
+		if err != nil {
+			return err
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: text,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if summary.Entries == 0 {
+		return nil, nil, fmt.Errorf("found no log entries for the invocation %s", id)
+	}
+	if props == nil && params.Unit != "" {
+		if p, latest := sj.latestInvocation(ctx, params.Unit); latest == id {
+			props = p
+		}
+	}
+	if props != nil {
+		summaryFromProperties(&summary, props)
+	}
+	if params.Count > 0 && len(txtContentList) > params.Count {
+		txtContentList = txtContentList[len(txtContentList)-params.Count:]
+	}
+	jsonByte, err := json.Marshal(struct {
+		Invocation InvocationSummary `json:"invocation"`
+	}{Invocation: summary})
+	if err != nil {
+		return nil, nil, err
+	}
+	txtContentList = append(txtContentList, &mcp.TextContent{
+		Text: string(jsonByte),
+	})
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+	}, nil, nil
+}
+
+// Coredumps returns the COREDUMP_ fields of the last count coredumps of
+// the unit, with the latest coredump first
+func (sj *HostLog) Coredumps(ctx context.Context, unit string, count int) ([]map[string]string, error) {
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	sj.journal.FlushMatches()
+	defer sj.journal.FlushMatches()
+	if err := sj.journal.AddMatch("MESSAGE_ID=" + messageCoredump); err != nil {
+		return nil, fmt.Errorf("failed to add coredump filter: %w", err)
+	}
+	if err := sj.journal.AddMatch("COREDUMP_UNIT=" + unit); err != nil {
+		return nil, fmt.Errorf("failed to add unit filter: %w", err)
+	}
+	if err := sj.journal.SeekTail(); err != nil {
+		return nil, fmt.Errorf("failed to seek to end: %w", err)
+	}
+	dumps := []map[string]string{}
+	for len(dumps) < count {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ret, err := sj.journal.Previous()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read previous entry: %w", err)
+		}
+		if ret == 0 {
+			break
+		}
+		entry, err := sj.journal.GetEntry()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entry: %w", err)
+		}
+		dump := map[string]string{}
+		for key, val := range entry.Fields {
+			// skip the core itself, if it's stored in the journal
+			if strings.HasPrefix(key, "COREDUMP_") && !isBinary(val) {
+				dump[key] = val
+			}
+		}
+		dumps = append(dumps, dump)
+	}
+	return dumps, nil
+}
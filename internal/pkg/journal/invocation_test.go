@@ -0,0 +1,58 @@
+package journal
+
+import (
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/sdjournal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeInvocationID(t *testing.T) {
+	id, err := normalizeInvocationID("0B4D1D4C-4F1A-4E8B-9C4D-1F0E2D3C4B5A")
+	assert.NoError(t, err)
+	assert.Equal(t, "0b4d1d4c4f1a4e8b9c4d1f0e2d3c4b5a", id)
+	_, err = normalizeInvocationID("latest-run")
+	assert.Error(t, err)
+	_, err = normalizeInvocationID("zz4d1d4c4f1a4e8b9c4d1f0e2d3c4b5a")
+	assert.Error(t, err)
+}
+
+func TestUpdateSummary(t *testing.T) {
+	entries := []*sdjournal.JournalEntry{
+		{Fields: map[string]string{"MESSAGE_ID": messageUnitStarted, "UNIT": "foo.service"}, RealtimeTimestamp: 1000000},
+		{Fields: map[string]string{"MESSAGE": "hello", "_SYSTEMD_UNIT": "foo.service"}, RealtimeTimestamp: 2000000},
+		{Fields: map[string]string{"MESSAGE_ID": messageUnitProcessExit, "EXIT_CODE": "exited", "EXIT_STATUS": "1"}, RealtimeTimestamp: 3000000},
+		{Fields: map[string]string{"MESSAGE_ID": messageUnitFailureResult, "UNIT_RESULT": "exit-code"}, RealtimeTimestamp: 4000000},
+	}
+	summary := InvocationSummary{InvocationID: "abc"}
+	for _, e := range entries {
+		updateSummary(&summary, e)
+	}
+	assert.Equal(t, "foo.service", summary.Unit)
+	assert.Equal(t, 4, summary.Entries)
+	assert.Equal(t, int64(1), summary.Start.Unix())
+	assert.Equal(t, int64(4), summary.End.Unix())
+	assert.True(t, summary.Finished)
+	assert.Equal(t, "exited", summary.ExitCode)
+	assert.Equal(t, "1", summary.ExitStatus)
+	assert.Equal(t, "exit-code", summary.Result)
+}
+
+func TestSummaryFromProperties(t *testing.T) {
+	running := InvocationSummary{}
+	summaryFromProperties(&running, map[string]interface{}{"ActiveState": "active", "Result": "success"})
+	assert.False(t, running.Finished)
+	assert.Empty(t, running.Result)
+
+	failed := InvocationSummary{}
+	summaryFromProperties(&failed, map[string]interface{}{
+		"ActiveState":    "failed",
+		"Result":         "signal",
+		"ExecMainCode":   int32(2),
+		"ExecMainStatus": int32(9),
+	})
+	assert.True(t, failed.Finished)
+	assert.Equal(t, "signal", failed.Result)
+	assert.Equal(t, "killed", failed.ExitCode)
+	assert.Equal(t, "9", failed.ExitStatus)
+}
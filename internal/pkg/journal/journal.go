@@ -2,8 +2,9 @@ package journal
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-systemd/v22/sdjournal"
@@ -11,7 +12,11 @@ import (
 )
 
 type HostLog struct {
+	// mu guards the journal, as the matches and the position are shared by
+	// all concurrent tool calls
+	mu      sync.Mutex
 	journal *sdjournal.Journal
+	units   UnitProperties
 }
 
 // NewLog instance creates a new HostLog instance
@@ -23,14 +28,25 @@ func NewLog() (*HostLog, error) {
 	return &HostLog{journal: j}, nil
 }
 
+// SetUnitProperties sets the source for the properties of the units, which
+// is used to resolve the latest invocation of a unit
+func (log *HostLog) SetUnitProperties(units UnitProperties) {
+	log.units = units
+}
+
 // Close the log and underlying journal
 func (log *HostLog) Close() error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
 	return log.journal.Close()
 }
 
 type ListLogParams struct {
-	Count int    `json:"count" jsonschema:"Number of log lines to output"`
-	Unit  string `json:"unit" jsonschema:"Exact name of the service/unit from which to get the logs. Without an unit name the entries of all units are returned. This parameter is optional."`
+	Count      int      `json:"count" jsonschema:"Number of log lines to output"`
+	Unit       string   `json:"unit" jsonschema:"Exact name of the service/unit from which to get the logs. Without an unit name the entries of all units are returned. This parameter is optional."`
+	Fields     []string `json:"fields,omitempty" jsonschema:"Additional journal fields to output for every entry, like PRIORITY, _PID, _COMM, CODE_FILE, CODE_LINE, _BOOT_ID or INVOCATION_ID. The keyword 'all' outputs all fields of the entry. Only used by the json, export and verbose output. This parameter is optional."`
+	Output     string   `json:"output,omitempty" jsonschema:"Format of the log entries, one of json, short, short-iso, cat, export or verbose. Defaults to json."`
+	Invocation string   `json:"invocation,omitempty" jsonschema:"Only return the entries of a single run of the unit, from its start to its exit. Takes an INVOCATION_ID or 'latest' or 'previous' for the latest run of the unit or the run before. All entries of the run are returned if count is 0. The last entry contains the exit status of the run. This parameter is optional."`
 }
 
 func (sj *HostLog) seekAndSkip(count uint64) (uint64, error) {
@@ -73,6 +89,16 @@ func (sj *HostLog) ListLogTimeout(ctx context.Context, req *mcp.CallToolRequest,
 
 // get the lat log entries for a given unit, else just the last messages
 func (sj *HostLog) ListLog(ctx context.Context, req *mcp.CallToolRequest, params *ListLogParams) (*mcp.CallToolResult, any, error) {
+	if params.Output != "" && !slices.Contains(ValidOutputs(), params.Output) {
+		return nil, nil, fmt.Errorf("invalid output format %s, valid formats are: %v", params.Output, ValidOutputs())
+	}
+	fields := normalizeFields(params.Fields)
+	if params.Invocation != "" {
+		return sj.listInvocation(ctx, params, fields)
+	}
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	sj.journal.FlushMatches()
 	if params.Unit != "" {
 		if err := sj.journal.AddMatch("SYSLOG_IDENTIFIER=" + params.Unit); err != nil {
 			return nil, nil, fmt.Errorf("failed to add unit filter: %w", err)
@@ -112,30 +138,12 @@ func (sj *HostLog) ListLog(ctx context.Context, req *mcp.CallToolRequest, params
 			return nil, nil, fmt.Errorf("failed to get entry: %w", err)
 		}
 
-		timestamp := time.Unix(0, int64(entry.RealtimeTimestamp)*int64(time.Microsecond))
-
-		structEntr := struct {
-			Time time.Time `json:"time"`
-			Unit string    `json:"unit"`
-			Host string    `json:"host"`
-			Msg  string    `json:"message"`
-			// Full map[string]string `json:"full"`
-		}{
-			Unit: entry.Fields["SYSLOG_IDENTIFIER"],
-			Time: timestamp,
-			Host: entry.Fields["_HOSTNAME"],
-			Msg:  entry.Fields["MESSAGE"],
-			// Full: entry.Fields,
-		}
-		if structEntr.Unit == "" {
-			structEntr.Unit = fmt.Sprintf("%s:%s", entry.Fields["_SYSTEMD_UNIT"], entry.Fields["_SYSTEMD_USER_UNIT"])
-		}
-		jsonByte, err := json.Marshal(&structEntr)
+		text, err := formatEntry(entry, params.Output, fields)
 		if err != nil {
 			return nil, nil, err
 		}
 		txtContentList = append(txtContentList, &mcp.TextContent{
-			Text: string(jsonByte),
+			Text: text,
 		})
 		ret, err := sj.journal.Next()
 		if err != nil {
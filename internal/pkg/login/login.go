@@ -0,0 +1,189 @@
+package login
+
+import (
+	"context"
+
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
+)
+
+// Inhibitor is an inhibitor lock, which delays or blocks shutdown, sleep or
+// idle. What is a colon separated list like 'shutdown:sleep' and the mode
+// is block or delay.
+type Inhibitor struct {
+	What string `json:"what"`
+	Who  string `json:"who"`
+	Why  string `json:"why"`
+	Mode string `json:"mode"`
+	UID  uint32 `json:"uid"`
+	PID  uint32 `json:"pid"`
+}
+
+// SessionEntry is an element of ListSessions
+type SessionEntry struct {
+	ID   string
+	UID  uint32
+	User string
+	Seat string
+	Path godbus.ObjectPath
+}
+
+// UserEntry is an element of ListUsers
+type UserEntry struct {
+	UID  uint32
+	Name string
+	Path godbus.ObjectPath
+}
+
+// SeatEntry is an element of ListSeats
+type SeatEntry struct {
+	ID   string
+	Path godbus.ObjectPath
+}
+
+// LogindConnection is an interface that abstracts the manager of logind on
+// the bus. This is primarily for testing purposes.
+type LogindConnection interface {
+	// Can calls a method like CanReboot, which returns yes, no, challenge
+	// or na
+	Can(ctx context.Context, method string) (string, error)
+	// PowerAction calls a method like Reboot or Suspend
+	PowerAction(ctx context.Context, method string, interactive bool) error
+	ScheduleShutdown(ctx context.Context, kind string, usec uint64) error
+	CancelScheduledShutdown(ctx context.Context) (bool, error)
+	ListInhibitors(ctx context.Context) ([]Inhibitor, error)
+	GetManagerProperties(ctx context.Context) (map[string]interface{}, error)
+	ListSessions(ctx context.Context) ([]SessionEntry, error)
+	ListUsers(ctx context.Context) ([]UserEntry, error)
+	ListSeats(ctx context.Context) ([]SeatEntry, error)
+	// GetProperties returns the properties of a session, user or seat
+	GetProperties(ctx context.Context, path godbus.ObjectPath, iface string) (map[string]interface{}, error)
+	TerminateSession(ctx context.Context, id string) error
+	SetUserLinger(ctx context.Context, uid uint32, enable bool, interactive bool) error
+	Close()
+}
+
+type Connection struct {
+	login  LogindConnection
+	policy *policy.Policy
+}
+
+// New connects to logind on the system bus
+func New(ctx context.Context) (*Connection, error) {
+	conn, err := newLogindConn()
+	if err != nil {
+		return nil, err
+	}
+	return &Connection{login: conn}, nil
+}
+
+// SetPolicy sets the policy which restricts the actions of the tools
+func (conn *Connection) SetPolicy(p *policy.Policy) {
+	conn.policy = p
+}
+
+// Close the connection to the bus
+func (conn *Connection) Close() {
+	conn.login.Close()
+}
+
+// the interfaces of the objects of logind on the bus
+const (
+	managerInterface = "org.freedesktop.login1.Manager"
+	sessionInterface = "org.freedesktop.login1.Session"
+	userInterface    = "org.freedesktop.login1.User"
+	seatInterface    = "org.freedesktop.login1.Seat"
+)
+
+// logindConn calls the methods of the logind manager
+type logindConn struct {
+	bus     *godbus.Conn
+	manager godbus.BusObject
+}
+
+func newLogindConn() (*logindConn, error) {
+	bus, err := godbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	return &logindConn{
+		bus:     bus,
+		manager: bus.Object("org.freedesktop.login1", "/org/freedesktop/login1"),
+	}, nil
+}
+
+func (conn *logindConn) Close() {
+	conn.bus.Close()
+}
+
+func (conn *logindConn) Can(ctx context.Context, method string) (string, error) {
+	var result string
+	err := conn.manager.CallWithContext(ctx, managerInterface+"."+method, 0).Store(&result)
+	return result, err
+}
+
+func (conn *logindConn) PowerAction(ctx context.Context, method string, interactive bool) error {
+	return conn.manager.CallWithContext(ctx, managerInterface+"."+method, 0, interactive).Store()
+}
+
+// ScheduleShutdown schedules a reboot or poweroff at the realtime timestamp
+// in usec
+func (conn *logindConn) ScheduleShutdown(ctx context.Context, kind string, usec uint64) error {
+	return conn.manager.CallWithContext(ctx, managerInterface+".ScheduleShutdown", 0, kind, usec).Store()
+}
+
+// CancelScheduledShutdown returns false if no shutdown was scheduled
+func (conn *logindConn) CancelScheduledShutdown(ctx context.Context) (bool, error) {
+	var cancelled bool
+	err := conn.manager.CallWithContext(ctx, managerInterface+".CancelScheduledShutdown", 0).Store(&cancelled)
+	return cancelled, err
+}
+
+func (conn *logindConn) ListInhibitors(ctx context.Context) ([]Inhibitor, error) {
+	var inhibitors []Inhibitor
+	err := conn.manager.CallWithContext(ctx, managerInterface+".ListInhibitors", 0).Store(&inhibitors)
+	return inhibitors, err
+}
+
+func (conn *logindConn) GetManagerProperties(ctx context.Context) (map[string]interface{}, error) {
+	return conn.GetProperties(ctx, conn.manager.Path(), managerInterface)
+}
+
+func (conn *logindConn) ListSessions(ctx context.Context) ([]SessionEntry, error) {
+	var sessions []SessionEntry
+	err := conn.manager.CallWithContext(ctx, managerInterface+".ListSessions", 0).Store(&sessions)
+	return sessions, err
+}
+
+func (conn *logindConn) ListUsers(ctx context.Context) ([]UserEntry, error) {
+	var users []UserEntry
+	err := conn.manager.CallWithContext(ctx, managerInterface+".ListUsers", 0).Store(&users)
+	return users, err
+}
+
+func (conn *logindConn) ListSeats(ctx context.Context) ([]SeatEntry, error) {
+	var seats []SeatEntry
+	err := conn.manager.CallWithContext(ctx, managerInterface+".ListSeats", 0).Store(&seats)
+	return seats, err
+}
+
+func (conn *logindConn) TerminateSession(ctx context.Context, id string) error {
+	return conn.manager.CallWithContext(ctx, managerInterface+".TerminateSession", 0, id).Store()
+}
+
+func (conn *logindConn) SetUserLinger(ctx context.Context, uid uint32, enable bool, interactive bool) error {
+	return conn.manager.CallWithContext(ctx, managerInterface+".SetUserLinger", 0, uid, enable, interactive).Store()
+}
+
+func (conn *logindConn) GetProperties(ctx context.Context, path godbus.ObjectPath, iface string) (map[string]interface{}, error) {
+	var props map[string]godbus.Variant
+	err := conn.bus.Object("org.freedesktop.login1", path).CallWithContext(ctx, "org.freedesktop.DBus.Properties.GetAll", 0, iface).Store(&props)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(props))
+	for key, value := range props {
+		out[key] = value.Value()
+	}
+	return out, nil
+}
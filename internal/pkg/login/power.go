@@ -0,0 +1,235 @@
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/systemd"
+)
+
+// realtimeNow returns the current time, it's overwritten in the tests
+var realtimeNow = time.Now
+
+// powerAction maps an action to the methods of logind and the inhibitor
+// lock which blocks it
+type powerAction struct {
+	method string
+	can    string
+	lock   string
+}
+
+var powerActions = map[string]powerAction{
+	"reboot":    {method: "Reboot", can: "CanReboot", lock: "shutdown"},
+	"poweroff":  {method: "PowerOff", can: "CanPowerOff", lock: "shutdown"},
+	"suspend":   {method: "Suspend", can: "CanSuspend", lock: "sleep"},
+	"hibernate": {method: "Hibernate", can: "CanHibernate", lock: "sleep"},
+}
+
+// ValidPowerActions returns the actions which can be triggered
+func ValidPowerActions() []string {
+	return slices.Sorted(maps.Keys(powerActions))
+}
+
+// blockingInhibitors returns the inhibitors which block the lock
+func blockingInhibitors(inhibitors []Inhibitor, lock string) []Inhibitor {
+	blocking := []Inhibitor{}
+	for _, inhibitor := range inhibitors {
+		if inhibitor.Mode == "block" && slices.Contains(strings.Split(inhibitor.What, ":"), lock) {
+			blocking = append(blocking, inhibitor)
+		}
+	}
+	return blocking
+}
+
+type ScheduledShutdown struct {
+	Kind string `json:"kind"`
+	Time string `json:"time"`
+}
+
+// scheduledShutdown reads the ScheduledShutdown property, which is empty if
+// no shutdown is scheduled
+func (conn *Connection) scheduledShutdown(ctx context.Context) (*ScheduledShutdown, error) {
+	props, err := conn.login.GetManagerProperties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, _ := props["ScheduledShutdown"].([]interface{})
+	if len(value) != 2 {
+		return nil, nil
+	}
+	kind, _ := value[0].(string)
+	usec, _ := value[1].(uint64)
+	if kind == "" || usec == 0 {
+		return nil, nil
+	}
+	return &ScheduledShutdown{
+		Kind: kind,
+		Time: time.UnixMicro(int64(usec)).Format(time.RFC3339),
+	}, nil
+}
+
+type PowerStatusParams struct{}
+
+type PowerStatus struct {
+	Capabilities      map[string]string  `json:"capabilities"`
+	ScheduledShutdown *ScheduledShutdown `json:"scheduled_shutdown,omitempty"`
+	Inhibitors        []Inhibitor        `json:"inhibitors"`
+}
+
+// show which power actions are possible, the scheduled shutdown and the
+// inhibitors
+func (conn *Connection) PowerStatus(ctx context.Context, req *mcp.CallToolRequest, params *PowerStatusParams) (*mcp.CallToolResult, any, error) {
+	status := PowerStatus{
+		Capabilities: make(map[string]string),
+		Inhibitors:   []Inhibitor{},
+	}
+	for _, action := range ValidPowerActions() {
+		can, err := conn.login.Can(ctx, powerActions[action].can)
+		if err != nil {
+			can = "unknown: " + err.Error()
+		}
+		status.Capabilities[action] = can
+	}
+	var err error
+	if status.ScheduledShutdown, err = conn.scheduledShutdown(ctx); err != nil {
+		return nil, nil, fmt.Errorf("couldn't get scheduled shutdown: %w", err)
+	}
+	inhibitors, err := conn.login.ListInhibitors(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't list inhibitors: %w", err)
+	}
+	status.Inhibitors = append(status.Inhibitors, inhibitors...)
+	jsonByte, err := json.Marshal(status)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
+
+type PowerActionParams struct {
+	Action           string `json:"action,omitempty" jsonschema:"Action to trigger: reboot, poweroff, suspend or hibernate."`
+	Delay            string `json:"delay,omitempty" jsonschema:"Schedule the reboot or poweroff after this time span like 5min or 1h instead of triggering it now. Users are warned by logind before the shutdown."`
+	Cancel           bool   `json:"cancel,omitempty" jsonschema:"Cancel the scheduled shutdown instead of triggering an action. Needs the cancel action to be allowed by the policy."`
+	IgnoreInhibitors bool   `json:"ignore_inhibitors,omitempty" jsonschema:"Trigger or schedule the action even if an inhibitor blocks it, a scheduled shutdown is not checked against the inhibitors again when it is due. Only use it if the inhibitors were checked and can be ignored."`
+}
+
+type PowerActionResult struct {
+	Action     string      `json:"action"`
+	Scheduled  string      `json:"scheduled,omitempty"`
+	Inhibitors []Inhibitor `json:"inhibitors,omitempty"`
+	Note       string      `json:"note,omitempty"`
+}
+
+// reboot, power off, suspend or hibernate the host now or schedule the
+// shutdown
+func (conn *Connection) PowerAction(ctx context.Context, req *mcp.CallToolRequest, params *PowerActionParams) (*mcp.CallToolResult, any, error) {
+	if params.Cancel {
+		// cancelling a shutdown which an administrator scheduled is a power
+		// action as well
+		if err := conn.policy.CheckPowerAction("cancel"); err != nil {
+			return nil, nil, err
+		}
+		cancelled, err := conn.login.CancelScheduledShutdown(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't cancel scheduled shutdown: %w", err)
+		}
+		text := "Cancelled the scheduled shutdown"
+		if !cancelled {
+			text = "No shutdown was scheduled"
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: text,
+				},
+			},
+		}, nil, nil
+	}
+	action, ok := powerActions[params.Action]
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid action %s, valid actions are: %v", params.Action, ValidPowerActions())
+	}
+	if err := conn.policy.CheckPowerAction(params.Action); err != nil {
+		return nil, nil, err
+	}
+	var usec uint64
+	if params.Delay != "" {
+		if action.lock != "shutdown" {
+			return nil, nil, fmt.Errorf("only reboot and poweroff can be scheduled")
+		}
+		var err error
+		if usec, err = systemd.ParseTimeSpan(params.Delay); err != nil || usec == systemd.Infinity {
+			return nil, nil, fmt.Errorf("invalid delay %s: %v", params.Delay, err)
+		}
+	}
+	can, err := conn.login.Can(ctx, action.can)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't check if %s is possible: %w", params.Action, err)
+	}
+	switch can {
+	case "yes":
+	case "challenge":
+		return nil, nil, fmt.Errorf("%s needs an interactive authentication, which the server can't do", params.Action)
+	default:
+		return nil, nil, fmt.Errorf("%s isn't possible on this host: %s", params.Action, can)
+	}
+	inhibitors, err := conn.login.ListInhibitors(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't list inhibitors: %w", err)
+	}
+	blocking := blockingInhibitors(inhibitors, action.lock)
+	result := PowerActionResult{Action: params.Action}
+	if len(blocking) > 0 {
+		// logind doesn't check the inhibitors again when a scheduled
+		// shutdown is due, so they are checked before scheduling it as well
+		if !params.IgnoreInhibitors {
+			who := []string{}
+			for _, inhibitor := range blocking {
+				who = append(who, fmt.Sprintf("%s (%s)", inhibitor.Who, inhibitor.Why))
+			}
+			return nil, nil, fmt.Errorf("%s is blocked by the inhibitors: %s. Call again with ignore_inhibitors to override them.", params.Action, strings.Join(who, ", "))
+		}
+		result.Inhibitors = blocking
+	}
+	if params.Delay != "" {
+		when := realtimeNow().Add(time.Duration(usec) * time.Microsecond)
+		if err := conn.login.ScheduleShutdown(ctx, params.Action, uint64(when.UnixMicro())); err != nil {
+			return nil, nil, fmt.Errorf("couldn't schedule %s: %w", params.Action, err)
+		}
+		result.Scheduled = when.Format(time.RFC3339)
+		result.Note = "the shutdown can be cancelled with cancel"
+		if len(blocking) > 0 {
+			result.Note += ", the blocking inhibitors were ignored"
+		}
+	} else {
+		if err := conn.login.PowerAction(ctx, action.method, false); err != nil {
+			return nil, nil, fmt.Errorf("couldn't %s: %w", params.Action, err)
+		}
+		if len(blocking) > 0 {
+			result.Note = "the blocking inhibitors were ignored"
+		}
+	}
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,196 @@
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockLogindConnection struct {
+	can                     func(method string) (string, error)
+	powerAction             func(method string, interactive bool) error
+	scheduleShutdown        func(kind string, usec uint64) error
+	cancelScheduledShutdown func() (bool, error)
+	listInhibitors          func() ([]Inhibitor, error)
+	managerProperties       func() (map[string]interface{}, error)
+	listSessions            func() ([]SessionEntry, error)
+	listUsers               func() ([]UserEntry, error)
+	listSeats               func() ([]SeatEntry, error)
+	properties              map[godbus.ObjectPath]map[string]interface{}
+	terminateSession        func(id string) error
+	setUserLinger           func(uid uint32, enable bool) error
+}
+
+func (m *mockLogindConnection) Can(ctx context.Context, method string) (string, error) {
+	return m.can(method)
+}
+
+func (m *mockLogindConnection) PowerAction(ctx context.Context, method string, interactive bool) error {
+	return m.powerAction(method, interactive)
+}
+
+func (m *mockLogindConnection) ScheduleShutdown(ctx context.Context, kind string, usec uint64) error {
+	return m.scheduleShutdown(kind, usec)
+}
+
+func (m *mockLogindConnection) CancelScheduledShutdown(ctx context.Context) (bool, error) {
+	return m.cancelScheduledShutdown()
+}
+
+func (m *mockLogindConnection) ListInhibitors(ctx context.Context) ([]Inhibitor, error) {
+	if m.listInhibitors == nil {
+		return nil, nil
+	}
+	return m.listInhibitors()
+}
+
+func (m *mockLogindConnection) GetManagerProperties(ctx context.Context) (map[string]interface{}, error) {
+	return m.managerProperties()
+}
+
+func (m *mockLogindConnection) ListSessions(ctx context.Context) ([]SessionEntry, error) {
+	return m.listSessions()
+}
+
+func (m *mockLogindConnection) ListUsers(ctx context.Context) ([]UserEntry, error) {
+	return m.listUsers()
+}
+
+func (m *mockLogindConnection) ListSeats(ctx context.Context) ([]SeatEntry, error) {
+	return m.listSeats()
+}
+
+func (m *mockLogindConnection) GetProperties(ctx context.Context, path godbus.ObjectPath, iface string) (map[string]interface{}, error) {
+	props, ok := m.properties[path]
+	if !ok {
+		return nil, fmt.Errorf("unknown object %s", path)
+	}
+	return props, nil
+}
+
+func (m *mockLogindConnection) TerminateSession(ctx context.Context, id string) error {
+	return m.terminateSession(id)
+}
+
+func (m *mockLogindConnection) SetUserLinger(ctx context.Context, uid uint32, enable bool, interactive bool) error {
+	return m.setUserLinger(uid, enable)
+}
+
+func (m *mockLogindConnection) Close() {}
+
+func TestPowerStatus(t *testing.T) {
+	scheduled := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	conn := &Connection{
+		login: &mockLogindConnection{
+			can: func(method string) (string, error) {
+				if method == "CanHibernate" {
+					return "na", nil
+				}
+				return "yes", nil
+			},
+			managerProperties: func() (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"ScheduledShutdown": []interface{}{"reboot", uint64(scheduled.UnixMicro())},
+				}, nil
+			},
+			listInhibitors: func() ([]Inhibitor, error) {
+				return []Inhibitor{{What: "shutdown:sleep", Who: "backup", Why: "running backup", Mode: "block", PID: 42}}, nil
+			},
+		},
+	}
+	res, _, err := conn.PowerStatus(context.Background(), nil, &PowerStatusParams{})
+	assert.NoError(t, err)
+	var status PowerStatus
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &status))
+	assert.Equal(t, map[string]string{"hibernate": "na", "poweroff": "yes", "reboot": "yes", "suspend": "yes"}, status.Capabilities)
+	assert.Equal(t, "reboot", status.ScheduledShutdown.Kind)
+	assert.Equal(t, scheduled.Local().Format(time.RFC3339), status.ScheduledShutdown.Time)
+	assert.Len(t, status.Inhibitors, 1)
+}
+
+func TestPowerAction(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	realtimeNow = func() time.Time { return now }
+	defer func() { realtimeNow = time.Now }()
+	var called []string
+	var scheduledAt uint64
+	inhibitors := []Inhibitor{
+		{What: "sleep", Who: "desktop", Why: "lid handling", Mode: "delay"},
+	}
+	mock := &mockLogindConnection{
+		can: func(method string) (string, error) {
+			if method == "CanHibernate" {
+				return "challenge", nil
+			}
+			return "yes", nil
+		},
+		powerAction: func(method string, interactive bool) error {
+			assert.False(t, interactive)
+			called = append(called, method)
+			return nil
+		},
+		scheduleShutdown: func(kind string, usec uint64) error {
+			called = append(called, "schedule "+kind)
+			scheduledAt = usec
+			return nil
+		},
+		cancelScheduledShutdown: func() (bool, error) { return true, nil },
+		listInhibitors:          func() ([]Inhibitor, error) { return inhibitors, nil },
+	}
+	conn := &Connection{login: mock}
+
+	_, _, err := conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "reboot"})
+	assert.ErrorContains(t, err, "-allow-power")
+	_, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Cancel: true})
+	assert.ErrorContains(t, err, "-allow-power")
+	conn.SetPolicy(&policy.Policy{AllowedPowerActions: []string{"reboot", "hibernate"}})
+	_, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "poweroff"})
+	assert.ErrorContains(t, err, "not allowed by the policy")
+	_, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "halt"})
+	assert.ErrorContains(t, err, "invalid action")
+	_, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Cancel: true})
+	assert.ErrorContains(t, err, "power action cancel is not allowed")
+	_, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "hibernate"})
+	assert.ErrorContains(t, err, "interactive authentication")
+	conn.SetPolicy(&policy.Policy{AllowedPowerActions: []string{"all"}})
+	_, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "suspend", Delay: "5min"})
+	assert.ErrorContains(t, err, "only reboot and poweroff")
+
+	// a delay inhibitor doesn't block
+	res, _, err := conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "suspend"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"action":"suspend"}`, res.Content[0].(*mcp.TextContent).Text)
+
+	inhibitors = append(inhibitors, Inhibitor{What: "shutdown:idle", Who: "zypper", Why: "updating packages", Mode: "block"})
+	_, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "reboot"})
+	assert.ErrorContains(t, err, "blocked by the inhibitors: zypper (updating packages)")
+	res, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "reboot", IgnoreInhibitors: true})
+	assert.NoError(t, err)
+	var result PowerActionResult
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Equal(t, "the blocking inhibitors were ignored", result.Note)
+	assert.Len(t, result.Inhibitors, 1)
+
+	// logind doesn't check the inhibitors when the scheduled shutdown is due
+	_, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "poweroff", Delay: "10min"})
+	assert.ErrorContains(t, err, "blocked by the inhibitors")
+	res, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Action: "poweroff", Delay: "10min", IgnoreInhibitors: true})
+	assert.NoError(t, err)
+	result = PowerActionResult{}
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Equal(t, now.Add(10*time.Minute).Format(time.RFC3339), result.Scheduled)
+	assert.Equal(t, uint64(now.Add(10*time.Minute).UnixMicro()), scheduledAt)
+	assert.Equal(t, "the shutdown can be cancelled with cancel, the blocking inhibitors were ignored", result.Note)
+	assert.Equal(t, []string{"Suspend", "Reboot", "schedule poweroff"}, called)
+
+	res, _, err = conn.PowerAction(context.Background(), nil, &PowerActionParams{Cancel: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "Cancelled the scheduled shutdown", res.Content[0].(*mcp.TextContent).Text)
+}
@@ -0,0 +1,317 @@
+package login
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// refID returns the id of a reference to another object like the (so) of
+// the seat of a session
+func refID(props map[string]interface{}, key string) string {
+	ref, _ := props[key].([]interface{})
+	if len(ref) == 0 {
+		return ""
+	}
+	return fmt.Sprint(ref[0])
+}
+
+// refIDs returns the ids of a list of references like the a(so) of the
+// sessions of a user
+func refIDs(props map[string]interface{}, key string) []string {
+	ids := []string{}
+	refs, _ := props[key].([][]interface{})
+	for _, ref := range refs {
+		if len(ref) > 0 {
+			ids = append(ids, fmt.Sprint(ref[0]))
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// usecTime returns a realtime timestamp in usec in RFC 3339 format or ""
+// if it isn't set
+func usecTime(props map[string]interface{}, key string) string {
+	usec, _ := props[key].(uint64)
+	if usec == 0 {
+		return ""
+	}
+	return time.UnixMicro(int64(usec)).Format(time.RFC3339)
+}
+
+// listResult returns every element as json in a separate content or the
+// note if the list is empty
+func listResult[T any](elems []T, empty string) (*mcp.CallToolResult, any, error) {
+	if len(elems) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: empty,
+				},
+			},
+		}, nil, nil
+	}
+	res := &mcp.CallToolResult{}
+	for _, elem := range elems {
+		jsonByte, err := json.Marshal(elem)
+		if err != nil {
+			return nil, nil, err
+		}
+		res.Content = append(res.Content, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	return res, nil, nil
+}
+
+type Session struct {
+	ID         string `json:"id"`
+	User       string `json:"user"`
+	UID        uint32 `json:"uid"`
+	Seat       string `json:"seat,omitempty"`
+	TTY        string `json:"tty,omitempty"`
+	Display    string `json:"display,omitempty"`
+	Remote     bool   `json:"remote"`
+	RemoteHost string `json:"remote_host,omitempty"`
+	RemoteUser string `json:"remote_user,omitempty"`
+	Service    string `json:"service,omitempty"`
+	Type       string `json:"type"`
+	Class      string `json:"class"`
+	State      string `json:"state"`
+	Active     bool   `json:"active"`
+	Idle       bool   `json:"idle"`
+	IdleSince  string `json:"idle_since,omitempty"`
+	Since      string `json:"since,omitempty"`
+	Leader     uint32 `json:"leader,omitempty"`
+}
+
+type ListSessionsParams struct {
+	User string `json:"user,omitempty" jsonschema:"Only list the sessions of this user, given by name or uid."`
+}
+
+// list the sessions like 'loginctl list-sessions'
+func (conn *Connection) ListSessions(ctx context.Context, req *mcp.CallToolRequest, params *ListSessionsParams) (*mcp.CallToolResult, any, error) {
+	entries, err := conn.login.ListSessions(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't list sessions: %w", err)
+	}
+	sessions := []Session{}
+	for _, entry := range entries {
+		if params.User != "" && params.User != entry.User && params.User != strconv.FormatUint(uint64(entry.UID), 10) {
+			continue
+		}
+		props, err := conn.login.GetProperties(ctx, entry.Path, sessionInterface)
+		if err != nil {
+			// the session may be closed in the meantime
+			continue
+		}
+		session := Session{ID: entry.ID, User: entry.User, UID: entry.UID, Seat: entry.Seat}
+		session.TTY, _ = props["TTY"].(string)
+		session.Display, _ = props["Display"].(string)
+		session.Remote, _ = props["Remote"].(bool)
+		session.RemoteHost, _ = props["RemoteHost"].(string)
+		session.RemoteUser, _ = props["RemoteUser"].(string)
+		session.Service, _ = props["Service"].(string)
+		session.Type, _ = props["Type"].(string)
+		session.Class, _ = props["Class"].(string)
+		session.State, _ = props["State"].(string)
+		session.Active, _ = props["Active"].(bool)
+		session.Idle, _ = props["IdleHint"].(bool)
+		if session.Idle {
+			session.IdleSince = usecTime(props, "IdleSinceHint")
+		}
+		session.Since = usecTime(props, "Timestamp")
+		session.Leader, _ = props["Leader"].(uint32)
+		sessions = append(sessions, session)
+	}
+	slices.SortFunc(sessions, func(a, b Session) int { return strings.Compare(a.ID, b.ID) })
+	return listResult(sessions, "no sessions found")
+}
+
+type LoginUser struct {
+	UID      uint32   `json:"uid"`
+	Name     string   `json:"name"`
+	State    string   `json:"state"`
+	Linger   bool     `json:"linger"`
+	Sessions []string `json:"sessions"`
+	Display  string   `json:"display,omitempty"`
+	Idle     bool     `json:"idle"`
+	Since    string   `json:"since,omitempty"`
+	Service  string   `json:"service,omitempty"`
+	Slice    string   `json:"slice,omitempty"`
+}
+
+type ListLoginUsersParams struct{}
+
+// list the logged in and lingering users like 'loginctl list-users'
+func (conn *Connection) ListLoginUsers(ctx context.Context, req *mcp.CallToolRequest, params *ListLoginUsersParams) (*mcp.CallToolResult, any, error) {
+	entries, err := conn.login.ListUsers(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't list users: %w", err)
+	}
+	users := []LoginUser{}
+	for _, entry := range entries {
+		props, err := conn.login.GetProperties(ctx, entry.Path, userInterface)
+		if err != nil {
+			continue
+		}
+		loginUser := LoginUser{UID: entry.UID, Name: entry.Name}
+		loginUser.State, _ = props["State"].(string)
+		loginUser.Linger, _ = props["Linger"].(bool)
+		loginUser.Sessions = refIDs(props, "Sessions")
+		loginUser.Display = refID(props, "Display")
+		loginUser.Idle, _ = props["IdleHint"].(bool)
+		loginUser.Since = usecTime(props, "Timestamp")
+		loginUser.Service, _ = props["Service"].(string)
+		loginUser.Slice, _ = props["Slice"].(string)
+		users = append(users, loginUser)
+	}
+	slices.SortFunc(users, func(a, b LoginUser) int { return cmp.Compare(a.UID, b.UID) })
+	return listResult(users, "no users found")
+}
+
+type Seat struct {
+	ID            string   `json:"id"`
+	ActiveSession string   `json:"active_session,omitempty"`
+	Sessions      []string `json:"sessions"`
+	CanGraphical  bool     `json:"can_graphical"`
+	CanTTY        bool     `json:"can_tty"`
+	Idle          bool     `json:"idle"`
+}
+
+type ListSeatsParams struct{}
+
+// list the seats like 'loginctl list-seats'
+func (conn *Connection) ListSeats(ctx context.Context, req *mcp.CallToolRequest, params *ListSeatsParams) (*mcp.CallToolResult, any, error) {
+	entries, err := conn.login.ListSeats(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't list seats: %w", err)
+	}
+	seats := []Seat{}
+	for _, entry := range entries {
+		props, err := conn.login.GetProperties(ctx, entry.Path, seatInterface)
+		if err != nil {
+			continue
+		}
+		seat := Seat{ID: entry.ID}
+		seat.ActiveSession = refID(props, "ActiveSession")
+		seat.Sessions = refIDs(props, "Sessions")
+		seat.CanGraphical, _ = props["CanGraphical"].(bool)
+		seat.CanTTY, _ = props["CanTTY"].(bool)
+		seat.Idle, _ = props["IdleHint"].(bool)
+		seats = append(seats, seat)
+	}
+	slices.SortFunc(seats, func(a, b Seat) int { return strings.Compare(a.ID, b.ID) })
+	return listResult(seats, "no seats found")
+}
+
+type ListInhibitorsParams struct {
+	What string `json:"what,omitempty" jsonschema:"Only list the inhibitors of this lock, like shutdown, sleep, idle or handle-lid-switch."`
+}
+
+// list the inhibitor locks like 'systemd-inhibit --list'
+func (conn *Connection) ListInhibitors(ctx context.Context, req *mcp.CallToolRequest, params *ListInhibitorsParams) (*mcp.CallToolResult, any, error) {
+	inhibitors, err := conn.login.ListInhibitors(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't list inhibitors: %w", err)
+	}
+	if params.What != "" {
+		inhibitors = slices.DeleteFunc(inhibitors, func(inhibitor Inhibitor) bool {
+			return !slices.Contains(strings.Split(inhibitor.What, ":"), params.What)
+		})
+	}
+	return listResult(inhibitors, "no inhibitors found")
+}
+
+type TerminateSessionParams struct {
+	ID string `json:"id" jsonschema:"Id of the session as listed by list_sessions."`
+}
+
+// terminate a session and kill its processes like 'loginctl terminate-session'
+func (conn *Connection) TerminateSession(ctx context.Context, req *mcp.CallToolRequest, params *TerminateSessionParams) (*mcp.CallToolResult, any, error) {
+	if params.ID == "" {
+		return nil, nil, fmt.Errorf("id of the session is required")
+	}
+	if err := conn.policy.CheckSessionAction("terminate"); err != nil {
+		return nil, nil, err
+	}
+	entries, err := conn.login.ListSessions(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't list sessions: %w", err)
+	}
+	idx := slices.IndexFunc(entries, func(entry SessionEntry) bool { return entry.ID == params.ID })
+	if idx < 0 {
+		return nil, nil, fmt.Errorf("no session with id %s", params.ID)
+	}
+	if err := conn.login.TerminateSession(ctx, params.ID); err != nil {
+		return nil, nil, fmt.Errorf("couldn't terminate session %s: %w", params.ID, err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Terminated session %s of %s", params.ID, entries[idx].User),
+			},
+		},
+	}, nil, nil
+}
+
+// lookupUser returns the uid and name of a user given by name or uid
+func lookupUser(name string) (uint32, string, error) {
+	if uid, err := strconv.ParseUint(name, 10, 32); err == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return uint32(uid), u.Username, nil
+		}
+		return uint32(uid), name, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, "", err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, "", err
+	}
+	return uint32(uid), u.Username, nil
+}
+
+type SetUserLingerParams struct {
+	User   string `json:"user" jsonschema:"Name or uid of the user."`
+	Enable bool   `json:"enable,omitempty" jsonschema:"Enable linger, so the user manager of the user is started at boot and keeps running without a session. Without it linger is disabled."`
+}
+
+// enable or disable linger of a user like 'loginctl enable-linger'
+func (conn *Connection) SetUserLinger(ctx context.Context, req *mcp.CallToolRequest, params *SetUserLingerParams) (*mcp.CallToolResult, any, error) {
+	if params.User == "" {
+		return nil, nil, fmt.Errorf("user is required")
+	}
+	if err := conn.policy.CheckSessionAction("linger"); err != nil {
+		return nil, nil, err
+	}
+	uid, name, err := lookupUser(params.User)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't find user %s: %w", params.User, err)
+	}
+	if err := conn.login.SetUserLinger(ctx, uid, params.Enable, false); err != nil {
+		return nil, nil, fmt.Errorf("couldn't set linger of %s: %w", name, err)
+	}
+	text := fmt.Sprintf("Disabled linger of %s (%d), the user manager is stopped after the last session", name, uid)
+	if params.Enable {
+		text = fmt.Sprintf("Enabled linger of %s (%d), the user manager keeps running without a session", name, uid)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: text,
+			},
+		},
+	}, nil, nil
+}
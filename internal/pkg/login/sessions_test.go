@@ -0,0 +1,166 @@
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListSessions(t *testing.T) {
+	since := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	conn := &Connection{
+		login: &mockLogindConnection{
+			listSessions: func() ([]SessionEntry, error) {
+				return []SessionEntry{
+					{ID: "4", UID: 1000, User: "alice", Path: "/org/freedesktop/login1/session/_34"},
+					{ID: "2", UID: 1000, User: "alice", Seat: "seat0", Path: "/org/freedesktop/login1/session/_32"},
+					{ID: "7", UID: 0, User: "root", Path: "/org/freedesktop/login1/session/_37"},
+				}, nil
+			},
+			properties: map[godbus.ObjectPath]map[string]interface{}{
+				"/org/freedesktop/login1/session/_32": {
+					"TTY": "tty2", "Type": "wayland", "Class": "user", "State": "active",
+					"Active": true, "Timestamp": uint64(since.UnixMicro()), "Leader": uint32(1234),
+				},
+				"/org/freedesktop/login1/session/_34": {
+					"Remote": true, "RemoteHost": "10.0.0.5", "Service": "sshd", "Type": "tty",
+					"Class": "user", "State": "online", "IdleHint": true, "IdleSinceHint": uint64(since.UnixMicro()),
+				},
+				"/org/freedesktop/login1/session/_37": {
+					"Type": "unspecified", "Class": "background", "State": "active",
+				},
+			},
+		},
+	}
+	res, _, err := conn.ListSessions(context.Background(), nil, &ListSessionsParams{User: "1000"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Content, 2)
+	var session Session
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &session))
+	assert.Equal(t, Session{
+		ID: "2", User: "alice", UID: 1000, Seat: "seat0", TTY: "tty2", Type: "wayland", Class: "user",
+		State: "active", Active: true, Since: since.Local().Format(time.RFC3339), Leader: 1234,
+	}, session)
+	session = Session{}
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[1].(*mcp.TextContent).Text), &session))
+	assert.Equal(t, "10.0.0.5", session.RemoteHost)
+	assert.True(t, session.Idle)
+	assert.Equal(t, since.Local().Format(time.RFC3339), session.IdleSince)
+
+	res, _, err = conn.ListSessions(context.Background(), nil, &ListSessionsParams{User: "bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, "no sessions found", res.Content[0].(*mcp.TextContent).Text)
+}
+
+func TestListLoginUsersAndSeats(t *testing.T) {
+	conn := &Connection{
+		login: &mockLogindConnection{
+			listUsers: func() ([]UserEntry, error) {
+				return []UserEntry{
+					{UID: 1000, Name: "alice", Path: "/org/freedesktop/login1/user/_1000"},
+					{UID: 470, Name: "runner", Path: "/org/freedesktop/login1/user/_470"},
+				}, nil
+			},
+			listSeats: func() ([]SeatEntry, error) {
+				return []SeatEntry{{ID: "seat0", Path: "/org/freedesktop/login1/seat/seat0"}}, nil
+			},
+			properties: map[godbus.ObjectPath]map[string]interface{}{
+				"/org/freedesktop/login1/user/_1000": {
+					"State":    "active",
+					"Sessions": [][]interface{}{{"4", godbus.ObjectPath("/s/4")}, {"2", godbus.ObjectPath("/s/2")}},
+					"Display":  []interface{}{"2", godbus.ObjectPath("/s/2")},
+					"Service":  "user@1000.service",
+				},
+				"/org/freedesktop/login1/user/_470": {
+					"State":  "lingering",
+					"Linger": true,
+				},
+				"/org/freedesktop/login1/seat/seat0": {
+					"ActiveSession": []interface{}{"2", godbus.ObjectPath("/s/2")},
+					"Sessions":      [][]interface{}{{"2", godbus.ObjectPath("/s/2")}},
+					"CanGraphical":  true,
+					"CanTTY":        true,
+				},
+			},
+		},
+	}
+	res, _, err := conn.ListLoginUsers(context.Background(), nil, &ListLoginUsersParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"uid":470,"name":"runner","state":"lingering","linger":true,"sessions":[],"idle":false}`, res.Content[0].(*mcp.TextContent).Text)
+	var loginUser LoginUser
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[1].(*mcp.TextContent).Text), &loginUser))
+	assert.Equal(t, []string{"2", "4"}, loginUser.Sessions)
+	assert.Equal(t, "2", loginUser.Display)
+
+	res, _, err = conn.ListSeats(context.Background(), nil, &ListSeatsParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"id":"seat0","active_session":"2","sessions":["2"],"can_graphical":true,"can_tty":true,"idle":false}`, res.Content[0].(*mcp.TextContent).Text)
+}
+
+func TestListInhibitors(t *testing.T) {
+	conn := &Connection{
+		login: &mockLogindConnection{
+			listInhibitors: func() ([]Inhibitor, error) {
+				return []Inhibitor{
+					{What: "shutdown:sleep", Who: "backup", Mode: "block"},
+					{What: "handle-lid-switch", Who: "desktop", Mode: "block"},
+				}, nil
+			},
+		},
+	}
+	res, _, err := conn.ListInhibitors(context.Background(), nil, &ListInhibitorsParams{What: "sleep"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Content, 1)
+	res, _, err = conn.ListInhibitors(context.Background(), nil, &ListInhibitorsParams{What: "idle"})
+	assert.NoError(t, err)
+	assert.Equal(t, "no inhibitors found", res.Content[0].(*mcp.TextContent).Text)
+}
+
+func TestSessionActions(t *testing.T) {
+	var terminated []string
+	var linger []bool
+	conn := &Connection{
+		login: &mockLogindConnection{
+			listSessions: func() ([]SessionEntry, error) {
+				return []SessionEntry{{ID: "4", UID: 1000, User: "alice"}}, nil
+			},
+			terminateSession: func(id string) error {
+				terminated = append(terminated, id)
+				return nil
+			},
+			setUserLinger: func(uid uint32, enable bool) error {
+				assert.Equal(t, uint32(0), uid)
+				linger = append(linger, enable)
+				return nil
+			},
+		},
+	}
+	_, _, err := conn.TerminateSession(context.Background(), nil, &TerminateSessionParams{ID: "4"})
+	assert.ErrorContains(t, err, "-allow-session")
+	_, _, err = conn.SetUserLinger(context.Background(), nil, &SetUserLingerParams{User: "0", Enable: true})
+	assert.ErrorContains(t, err, "-allow-session")
+
+	conn.SetPolicy(&policy.Policy{AllowedSessionActions: []string{"linger"}})
+	_, _, err = conn.TerminateSession(context.Background(), nil, &TerminateSessionParams{ID: "4"})
+	assert.ErrorContains(t, err, "not allowed by the policy")
+	res, _, err := conn.SetUserLinger(context.Background(), nil, &SetUserLingerParams{User: "0", Enable: true})
+	assert.NoError(t, err)
+	assert.Contains(t, res.Content[0].(*mcp.TextContent).Text, "Enabled linger of")
+
+	conn.SetPolicy(&policy.Policy{AllowedSessionActions: []string{"all"}})
+	_, _, err = conn.TerminateSession(context.Background(), nil, &TerminateSessionParams{ID: "9"})
+	assert.ErrorContains(t, err, "no session with id 9")
+	res, _, err = conn.TerminateSession(context.Background(), nil, &TerminateSessionParams{ID: "4"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Terminated session 4 of alice", res.Content[0].(*mcp.TextContent).Text)
+	_, _, err = conn.SetUserLinger(context.Background(), nil, &SetUserLingerParams{User: "0"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4"}, terminated)
+	assert.Equal(t, []bool{true, false}, linger)
+}
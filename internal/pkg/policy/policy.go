@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Policy restricts the actions of the tools on the host beyond the
+// permissions of the user the server runs as. The zero value denies
+// everything which needs an explicit permission.
+type Policy struct {
+	// AllowedExecutables are glob patterns of the absolute paths of the
+	// executables which may be started as transient units
+	AllowedExecutables []string
+	// AllowedPowerActions are the power actions like reboot or poweroff
+	// which may be triggered, 'all' allows every action
+	AllowedPowerActions []string
+	// AllowedSessionActions are the actions on the sessions and users of
+	// logind like terminate or linger, 'all' allows every action
+	AllowedSessionActions []string
+	// AllowedUsers are the users which transient units may run as, 'all'
+	// allows every user
+	AllowedUsers []string
+	// AllowedDirectives are the settings like BindPaths= which may be set
+	// in drop-ins although they can change which executable is run
+	AllowedDirectives []string
+}
+
+// ParseList splits a comma separated flag value into its elements
+func ParseList(value string) (ret []string) {
+	for _, elem := range strings.Split(value, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			ret = append(ret, elem)
+		}
+	}
+	return ret
+}
+
+// CheckExecutable returns an error if the executable with the given
+// absolute path may not be run
+func (p *Policy) CheckExecutable(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("executable must be an absolute path: %s", path)
+	}
+	path = filepath.Clean(path)
+	if p == nil || len(p.AllowedExecutables) == 0 {
+		return fmt.Errorf("running executables is not allowed, start the server with -allow-exec to allow %s", path)
+	}
+	for _, pattern := range p.AllowedExecutables {
+		if match, err := filepath.Match(pattern, path); err == nil && match {
+			return nil
+		}
+	}
+	return fmt.Errorf("executable %s is not allowed by the policy, allowed are: %v", path, p.AllowedExecutables)
+}
+
+// dangerousEnvironment are the environment variables which make an allowed
+// executable load or run other code, a trailing '*' matches a prefix
+var dangerousEnvironment = []string{
+	"LD_*", "PATH", "GCONV_PATH", "LOCPATH", "HOSTALIASES", "BASH_ENV", "ENV",
+	"SHELLOPTS", "BASHOPTS", "IFS", "PS4", "PROMPT_COMMAND", "PYTHON*", "PERL5*",
+	"PERLLIB", "RUBY*", "NODE_OPTIONS", "NODE_PATH", "JAVA_TOOL_OPTIONS",
+	"_JAVA_OPTIONS", "JDK_JAVA_OPTIONS", "CLASSPATH", "LUA_*", "TCLLIBPATH",
+	"GIT_*", "SYSTEMD_*",
+}
+
+// CheckEnvironment returns an error if an assignment of the form VAR=value
+// is invalid or sets a variable which could be used to run other code than
+// the allowed executable, like LD_PRELOAD or PATH
+func CheckEnvironment(env []string) error {
+	for _, assignment := range env {
+		name, _, found := strings.Cut(assignment, "=")
+		if !found || name == "" {
+			return fmt.Errorf("invalid environment assignment, expected VAR=value: %s", assignment)
+		}
+		for _, pattern := range dangerousEnvironment {
+			prefix, isPrefix := strings.CutSuffix(pattern, "*")
+			if name == pattern || isPrefix && strings.HasPrefix(name, prefix) {
+				return fmt.Errorf("setting the environment variable %s is not allowed, it can be used to run other executables", name)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckUser returns an error if a transient unit may not run as the user
+func (p *Policy) CheckUser(user string) error {
+	if p == nil || len(p.AllowedUsers) == 0 {
+		return fmt.Errorf("running commands as another user is not allowed, start the server with -allow-user to allow %s", user)
+	}
+	if slices.Contains(p.AllowedUsers, "all") || slices.Contains(p.AllowedUsers, user) {
+		return nil
+	}
+	return fmt.Errorf("user %s is not allowed by the policy, allowed are: %v", user, p.AllowedUsers)
+}
+
+// CheckDirective returns an error if the setting may not be set in a
+// drop-in, as it can change which executable is run
+func (p *Policy) CheckDirective(name string) error {
+	if p == nil || len(p.AllowedDirectives) == 0 {
+		return fmt.Errorf("%s can change which executables are run, start the server with -allow-directive to allow it", name)
+	}
+	if slices.Contains(p.AllowedDirectives, name) {
+		return nil
+	}
+	return fmt.Errorf("setting %s is not allowed by the policy, allowed are: %v", name, p.AllowedDirectives)
+}
+
+// CheckPowerAction returns an error if the power action like reboot may not
+// be triggered
+func (p *Policy) CheckPowerAction(action string) error {
+	if p == nil || len(p.AllowedPowerActions) == 0 {
+		return fmt.Errorf("power actions are not allowed, start the server with -allow-power to allow %s", action)
+	}
+	if slices.Contains(p.AllowedPowerActions, "all") || slices.Contains(p.AllowedPowerActions, action) {
+		return nil
+	}
+	return fmt.Errorf("power action %s is not allowed by the policy, allowed are: %v", action, p.AllowedPowerActions)
+}
+
+// CheckSessionAction returns an error if the action on a session or user
+// like terminate or linger may not be done
+func (p *Policy) CheckSessionAction(action string) error {
+	if p == nil || len(p.AllowedSessionActions) == 0 {
+		return fmt.Errorf("session actions are not allowed, start the server with -allow-session to allow %s", action)
+	}
+	if slices.Contains(p.AllowedSessionActions, "all") || slices.Contains(p.AllowedSessionActions, action) {
+		return nil
+	}
+	return fmt.Errorf("session action %s is not allowed by the policy, allowed are: %v", action, p.AllowedSessionActions)
+}
@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckExecutable(t *testing.T) {
+	var p *Policy
+	assert.ErrorContains(t, p.CheckExecutable("/usr/bin/true"), "-allow-exec")
+	p = &Policy{AllowedExecutables: ParseList("/usr/bin/*, /opt/tool/run ,")}
+	assert.Equal(t, []string{"/usr/bin/*", "/opt/tool/run"}, p.AllowedExecutables)
+	assert.NoError(t, p.CheckExecutable("/usr/bin/true"))
+	assert.NoError(t, p.CheckExecutable("/opt/tool/run"))
+	assert.Error(t, p.CheckExecutable("/usr/bin/../sbin/reboot"))
+	assert.Error(t, p.CheckExecutable("/usr/bin/sub/dir"))
+	assert.Error(t, p.CheckExecutable("true"))
+}
+
+func TestCheckPowerAction(t *testing.T) {
+	var p *Policy
+	assert.ErrorContains(t, p.CheckPowerAction("reboot"), "-allow-power")
+	p = &Policy{AllowedPowerActions: ParseList("reboot,suspend")}
+	assert.NoError(t, p.CheckPowerAction("reboot"))
+	assert.NoError(t, p.CheckPowerAction("suspend"))
+	assert.Error(t, p.CheckPowerAction("poweroff"))
+	p = &Policy{AllowedPowerActions: []string{"all"}}
+	assert.NoError(t, p.CheckPowerAction("hibernate"))
+}
+
+func TestCheckSessionAction(t *testing.T) {
+	var p *Policy
+	assert.ErrorContains(t, p.CheckSessionAction("terminate"), "-allow-session")
+	p = &Policy{AllowedSessionActions: ParseList("linger")}
+	assert.NoError(t, p.CheckSessionAction("linger"))
+	assert.Error(t, p.CheckSessionAction("terminate"))
+	p = &Policy{AllowedSessionActions: []string{"all"}}
+	assert.NoError(t, p.CheckSessionAction("terminate"))
+}
+
+func TestCheckEnvironment(t *testing.T) {
+	assert.NoError(t, CheckEnvironment([]string{"FOO=bar", "LANG=C.UTF-8", "EMPTY="}))
+	for _, env := range []string{"LD_PRELOAD=/tmp/x.so", "PATH=/tmp", "PYTHONPATH=/tmp", "BASH_ENV=/tmp/rc", "NOEQUALS", "=value"} {
+		assert.Error(t, CheckEnvironment([]string{"FOO=bar", env}), env)
+	}
+}
+
+func TestCheckDirective(t *testing.T) {
+	var p *Policy
+	assert.ErrorContains(t, p.CheckDirective("BindPaths"), "-allow-directive")
+	p = &Policy{AllowedDirectives: ParseList("BindReadOnlyPaths")}
+	assert.NoError(t, p.CheckDirective("BindReadOnlyPaths"))
+	assert.ErrorContains(t, p.CheckDirective("RootDirectory"), "not allowed by the policy")
+}
+
+func TestCheckUser(t *testing.T) {
+	var p *Policy
+	assert.ErrorContains(t, p.CheckUser("root"), "-allow-user")
+	p = &Policy{AllowedUsers: ParseList("nobody,backup")}
+	assert.NoError(t, p.CheckUser("backup"))
+	assert.ErrorContains(t, p.CheckUser("root"), "not allowed by the policy")
+	p = &Policy{AllowedUsers: []string{"all"}}
+	assert.NoError(t, p.CheckUser("root"))
+}
@@ -0,0 +1,237 @@
+package systemd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// MaxChainLength limits the length of the critical chain, to stop on
+// ordering cycles
+const MaxChainLength = 100
+
+type AnalyzeBootParams struct {
+	Target string `json:"target,omitempty" jsonschema:"Unit to compute the critical chain for, defaults to default.target."`
+	Top    int    `json:"top,omitempty" jsonschema:"Number of the slowest units to return, defaults to 20."`
+}
+
+// BootTimes are the durations of the boot phases, like 'systemd-analyze time'
+type BootTimes struct {
+	Firmware  string `json:"firmware,omitempty"`
+	Loader    string `json:"loader,omitempty"`
+	Kernel    string `json:"kernel"`
+	InitRD    string `json:"initrd,omitempty"`
+	Userspace string `json:"userspace"`
+	Total     string `json:"total"`
+	Finished  bool   `json:"finished"`
+}
+
+type BlameEntry struct {
+	Unit string `json:"unit"`
+	Time string `json:"time"`
+}
+
+// ChainLink is a unit of the critical chain with the time it became active
+// after the start of the userspace and the time it took to start
+type ChainLink struct {
+	Unit      string `json:"unit"`
+	ActiveAt  string `json:"active_at"`
+	StartTime string `json:"start_time,omitempty"`
+}
+
+type BootAnalysis struct {
+	Times         BootTimes    `json:"times"`
+	Blame         []BlameEntry `json:"blame"`
+	CriticalChain []ChainLink  `json:"critical_chain"`
+	Notes         []string     `json:"notes,omitempty"`
+}
+
+// unitTimes are the monotonic timestamps of the activation of a unit
+type unitTimes struct {
+	activating uint64
+	activated  uint64
+	after      []string
+}
+
+// usecDuration returns the duration rounded to milliseconds as systemd-analyze
+func usecDuration(usec uint64) string {
+	return (time.Duration(usec) * time.Microsecond).Round(time.Millisecond).String()
+}
+
+// bootTimes calculates the duration of the boot phases from the manager
+// properties. The firmware and loader timestamps count backwards from the
+// start of the kernel.
+func bootTimes(props map[string]interface{}) BootTimes {
+	firmware, _ := props["FirmwareTimestampMonotonic"].(uint64)
+	loader, _ := props["LoaderTimestampMonotonic"].(uint64)
+	kernel, _ := props["KernelTimestampMonotonic"].(uint64)
+	initrd, _ := props["InitRDTimestampMonotonic"].(uint64)
+	userspace, _ := props["UserspaceTimestampMonotonic"].(uint64)
+	finish, _ := props["FinishTimestampMonotonic"].(uint64)
+	times := BootTimes{Finished: finish > 0}
+	if firmware > 0 && firmware >= loader {
+		times.Firmware = usecDuration(firmware - loader)
+	}
+	if loader > 0 {
+		times.Loader = usecDuration(loader)
+	}
+	if initrd > 0 {
+		times.Kernel = usecDuration(initrd - kernel)
+		times.InitRD = usecDuration(userspace - initrd)
+	} else {
+		times.Kernel = usecDuration(userspace - kernel)
+	}
+	if finish > 0 {
+		times.Userspace = usecDuration(finish - userspace)
+		times.Total = usecDuration(firmware + finish - kernel)
+	}
+	return times
+}
+
+// collectUnitTimes reads the activation timestamps of all units
+func (conn *Connection) collectUnitTimes(ctx context.Context) (map[string]unitTimes, error) {
+	units, err := conn.dbus.ListUnitsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]unitTimes, len(units))
+	for _, unit := range units {
+		props, err := conn.dbus.GetAllPropertiesContext(ctx, unit.Name)
+		if err != nil {
+			continue
+		}
+		times := unitTimes{after: stringSlice(props, "After")}
+		times.activating, _ = props["InactiveExitTimestampMonotonic"].(uint64)
+		times.activated, _ = props["ActiveEnterTimestampMonotonic"].(uint64)
+		ret[unit.Name] = times
+	}
+	return ret, nil
+}
+
+// blame returns the units sorted by the time they took to start
+func blame(units map[string]unitTimes, top int) []BlameEntry {
+	type entry struct {
+		unit string
+		usec uint64
+	}
+	entries := []entry{}
+	for name, times := range units {
+		if times.activating == 0 || times.activated <= times.activating {
+			continue
+		}
+		entries = append(entries, entry{unit: name, usec: times.activated - times.activating})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		if c := cmp.Compare(b.usec, a.usec); c != 0 {
+			return c
+		}
+		return strings.Compare(a.unit, b.unit)
+	})
+	if len(entries) > top {
+		entries = entries[:top]
+	}
+	ret := []BlameEntry{}
+	for _, e := range entries {
+		ret = append(ret, BlameEntry{Unit: e.unit, Time: usecDuration(e.usec)})
+	}
+	return ret
+}
+
+// criticalChain follows the After dependencies from the target back to the
+// start of the userspace. Like systemd-analyze the dependency which became
+// active last is the one delaying the unit.
+func criticalChain(units map[string]unitTimes, target string, userspace, finish uint64) []ChainLink {
+	chain := []ChainLink{}
+	visited := map[string]bool{}
+	name := target
+	for len(chain) < MaxChainLength && name != "" && !visited[name] {
+		visited[name] = true
+		times := units[name]
+		link := ChainLink{Unit: name}
+		if times.activated >= userspace {
+			link.ActiveAt = "+" + usecDuration(times.activated-userspace)
+		}
+		if times.activating > 0 && times.activated > times.activating {
+			link.StartTime = usecDuration(times.activated - times.activating)
+		}
+		chain = append(chain, link)
+		next := ""
+		var latest uint64
+		for _, dep := range times.after {
+			depTimes, ok := units[dep]
+			if !ok || depTimes.activated == 0 || visited[dep] {
+				continue
+			}
+			if finish > 0 && depTimes.activated > finish {
+				continue
+			}
+			if times.activating > 0 && depTimes.activated > times.activating {
+				continue
+			}
+			if depTimes.activated > latest || depTimes.activated == latest && dep < next {
+				latest = depTimes.activated
+				next = dep
+			}
+		}
+		name = next
+	}
+	return chain
+}
+
+// analyze the boot time, the slowest units and the critical chain
+func (conn *Connection) AnalyzeBoot(ctx context.Context, req *mcp.CallToolRequest, params *AnalyzeBootParams) (*mcp.CallToolResult, any, error) {
+	target := params.Target
+	if target == "" {
+		target = "default.target"
+	}
+	top := params.Top
+	if top <= 0 {
+		top = 20
+	}
+	managerProps, err := conn.dbus.GetManagerPropertiesContext(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get manager properties: %w", err)
+	}
+	analysis := BootAnalysis{Times: bootTimes(managerProps)}
+	if !analysis.Times.Finished {
+		analysis.Notes = append(analysis.Notes, "the boot hasn't finished yet, the times are incomplete")
+	}
+	// resolve aliases like default.target to the name of the unit
+	targetProps, err := conn.dbus.GetAllPropertiesContext(ctx, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if id, ok := targetProps["Id"].(string); ok && id != "" {
+		target = id
+	}
+	units, err := conn.collectUnitTimes(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	userspace, _ := managerProps["UserspaceTimestampMonotonic"].(uint64)
+	finish, _ := managerProps["FinishTimestampMonotonic"].(uint64)
+	analysis.Blame = blame(units, top)
+	if _, ok := units[target]; ok {
+		analysis.CriticalChain = criticalChain(units, target, userspace, finish)
+	} else {
+		analysis.CriticalChain = []ChainLink{}
+		analysis.Notes = append(analysis.Notes, fmt.Sprintf("%s isn't loaded, no critical chain available", target))
+	}
+	jsonByte, err := json.Marshal(analysis)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
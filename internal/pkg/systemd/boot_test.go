@@ -0,0 +1,70 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeBoot(t *testing.T) {
+	// timestamps in usec, userspace starts at 2s
+	units := map[string]map[string]interface{}{
+		"graphical.target":        {"Id": "graphical.target", "After": []string{"multi-user.target", "display-manager.service"}, "InactiveExitTimestampMonotonic": uint64(9000000), "ActiveEnterTimestampMonotonic": uint64(9000000)},
+		"multi-user.target":       {"After": []string{"network.target", "slow.service", "fast.service"}, "InactiveExitTimestampMonotonic": uint64(8000000), "ActiveEnterTimestampMonotonic": uint64(8000000)},
+		"display-manager.service": {"After": []string{"fast.service"}, "InactiveExitTimestampMonotonic": uint64(4000000), "ActiveEnterTimestampMonotonic": uint64(4500000)},
+		"slow.service":            {"After": []string{"network.target"}, "InactiveExitTimestampMonotonic": uint64(3000000), "ActiveEnterTimestampMonotonic": uint64(7500000)},
+		"fast.service":            {"After": []string{"network.target"}, "InactiveExitTimestampMonotonic": uint64(3000000), "ActiveEnterTimestampMonotonic": uint64(3100000)},
+		"network.target":          {"After": []string{"graphical.target"}, "InactiveExitTimestampMonotonic": uint64(2900000), "ActiveEnterTimestampMonotonic": uint64(2900000)},
+	}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			managerProperties: func() (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"FirmwareTimestampMonotonic":  uint64(5000000),
+					"LoaderTimestampMonotonic":    uint64(1000000),
+					"KernelTimestampMonotonic":    uint64(0),
+					"InitRDTimestampMonotonic":    uint64(1500000),
+					"UserspaceTimestampMonotonic": uint64(2000000),
+					"FinishTimestampMonotonic":    uint64(9000000),
+				}, nil
+			},
+			listUnits: func() ([]dbus.UnitStatus, error) {
+				ret := []dbus.UnitStatus{}
+				for name := range units {
+					ret = append(ret, dbus.UnitStatus{Name: name})
+				}
+				return ret, nil
+			},
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				if unitName == "default.target" {
+					unitName = "graphical.target"
+				}
+				return units[unitName], nil
+			},
+		},
+	}
+	res, _, err := conn.AnalyzeBoot(context.Background(), nil, &AnalyzeBootParams{Top: 2})
+	assert.NoError(t, err)
+	var analysis BootAnalysis
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &analysis))
+	assert.Equal(t, BootTimes{
+		Firmware:  "4s",
+		Loader:    "1s",
+		Kernel:    "1.5s",
+		InitRD:    "500ms",
+		Userspace: "7s",
+		Total:     "14s",
+		Finished:  true,
+	}, analysis.Times)
+	assert.Equal(t, []BlameEntry{{Unit: "slow.service", Time: "4.5s"}, {Unit: "display-manager.service", Time: "500ms"}}, analysis.Blame)
+	assert.Equal(t, []ChainLink{
+		{Unit: "graphical.target", ActiveAt: "+7s"},
+		{Unit: "multi-user.target", ActiveAt: "+6s"},
+		{Unit: "slow.service", ActiveAt: "+5.5s", StartTime: "4.5s"},
+		{Unit: "network.target", ActiveAt: "+900ms"},
+	}, analysis.CriticalChain)
+}
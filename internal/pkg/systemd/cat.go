@@ -0,0 +1,174 @@
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// unitSearchPaths are the directories in which systemd looks for unit files
+// and drop-ins of the system and user manager, see systemd.unit(5)
+var unitSearchPaths = []string{
+	"/etc/systemd/system.control",
+	"/run/systemd/system.control",
+	"/run/systemd/transient",
+	"/run/systemd/generator.early",
+	"/etc/systemd/system",
+	"/etc/systemd/system.attached",
+	"/run/systemd/system",
+	"/run/systemd/system.attached",
+	"/run/systemd/generator",
+	"/usr/local/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+	"/run/systemd/generator.late",
+	"/etc/systemd/user",
+	"/run/systemd/user",
+	"/usr/local/lib/systemd/user",
+	"/usr/lib/systemd/user",
+	"/lib/systemd/user",
+}
+
+// userSearchPaths returns the search paths in the home of the user
+func userSearchPaths() (paths []string) {
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config/systemd/user"), filepath.Join(home, ".local/share/systemd/user"))
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "systemd/user"))
+	}
+	if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "systemd/user"), filepath.Join(xdg, "systemd/transient"))
+	}
+	return paths
+}
+
+// InUnitSearchPath checks if the path is in one of the unit search paths
+func InUnitSearchPath(path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	path = filepath.Clean(path)
+	for _, dir := range slices.Concat(unitSearchPaths, userSearchPaths()) {
+		if strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readUnitFile reads a file of a unit if it's in the search paths. A link
+// in a search path may point to any file, so its target has to be in the
+// search paths as well.
+func readUnitFile(path string) ([]byte, error) {
+	if !InUnitSearchPath(path) {
+		return nil, fmt.Errorf("%s is not in the unit search paths", path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	if !InUnitSearchPath(resolved) {
+		return nil, fmt.Errorf("%s links to %s, which is not in the unit search paths", path, resolved)
+	}
+	// don't follow a link which was created after resolving the path
+	file, err := os.OpenFile(resolved, os.O_RDONLY|syscall.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+type CatUnitParams struct {
+	Name string `json:"name" jsonschema:"Exact name of the unit, like foo.service."`
+}
+
+type CatUnitFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type CatUnitResult struct {
+	Unit     string                         `json:"unit"`
+	Files    []CatUnitFile                  `json:"files"`
+	Settings map[string]map[string][]string `json:"settings"`
+	Errors   []UnitFileError                `json:"errors,omitempty"`
+}
+
+// catUnit reads the fragment and the drop-ins of the unit and merges the
+// settings
+func (conn *Connection) catUnit(ctx context.Context, name string) (*CatUnitResult, error) {
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	fragment, _ := props["FragmentPath"].(string)
+	if fragment == "/dev/null" {
+		return nil, fmt.Errorf("%s is masked", name)
+	}
+	paths := []string{}
+	if fragment != "" {
+		paths = append(paths, fragment)
+	}
+	paths = append(paths, stringSlice(props, "DropInPaths")...)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no unit file found for %s", name)
+	}
+	result := &CatUnitResult{
+		Unit:  name,
+		Files: []CatUnitFile{},
+	}
+	opts := []UnitOption{}
+	for _, path := range paths {
+		file := CatUnitFile{Path: path}
+		content, err := readUnitFile(path)
+		if err != nil {
+			file.Error = err.Error()
+			result.Files = append(result.Files, file)
+			continue
+		}
+		file.Content = string(content)
+		result.Files = append(result.Files, file)
+		fileOpts, fileErrs, err := ParseUnitFile(bytes.NewReader(content), path)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, fileOpts...)
+		result.Errors = append(result.Errors, fileErrs...)
+	}
+	result.Settings = MergeUnitOptions(opts)
+	return result, nil
+}
+
+// show the unit file, its drop-ins and the effective settings
+func (conn *Connection) CatUnit(ctx context.Context, req *mcp.CallToolRequest, params *CatUnitParams) (*mcp.CallToolResult, any, error) {
+	if params.Name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	result, err := conn.catUnit(ctx, params.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
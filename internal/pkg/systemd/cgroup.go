@@ -0,0 +1,385 @@
+package systemd
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// cgroupRoot is the mount point of the unified cgroup v2 hierarchy
+var cgroupRoot = "/sys/fs/cgroup"
+
+// MaxSampleInterval limits the time the CPU usage is sampled
+const MaxSampleInterval = 5 * time.Second
+
+// memoryStatKeys are the entries of memory.stat which are reported
+var memoryStatKeys = []string{
+	"anon", "file", "kernel", "kernel_stack", "slab", "sock", "shmem",
+	"file_dirty", "file_writeback", "swapcached", "pgmajfault",
+}
+
+// unitCgroupDir returns the directory of the control group of the unit
+func (conn *Connection) unitCgroupDir(ctx context.Context, name string) (cgroup, dir string, err error) {
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+	cgroup, _ = props["ControlGroup"].(string)
+	if cgroup == "" {
+		return "", "", fmt.Errorf("%s has no control group, it's not running", name)
+	}
+	return cgroup, filepath.Join(cgroupRoot, filepath.Clean("/"+cgroup)), nil
+}
+
+// readCgroupValue reads a file of the cgroup with a single value, 'max' is
+// returned as Infinity
+func readCgroupValue(dir, file string) (uint64, bool) {
+	content, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0, false
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "max" {
+		return Infinity, true
+	}
+	val, err := strconv.ParseUint(value, 10, 64)
+	return val, err == nil
+}
+
+// readCgroupKeyValues reads a flat keyed file of the cgroup like cpu.stat
+func readCgroupKeyValues(dir, file string) map[string]uint64 {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	ret := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if val, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			ret[fields[0]] = val
+		}
+	}
+	return ret
+}
+
+// IOStat is the sum of io.stat over all devices, the rates are sampled
+// like the CPU usage
+type IOStat struct {
+	ReadBytes        uint64 `json:"read_bytes"`
+	WriteBytes       uint64 `json:"write_bytes"`
+	ReadIOs          uint64 `json:"read_ios"`
+	WriteIOs         uint64 `json:"write_ios"`
+	ReadBytesPerSec  uint64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec uint64 `json:"write_bytes_per_sec"`
+}
+
+// readIOStat sums the nested keyed io.stat, which has a line per device
+// like '8:0 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=0 dios=0'
+func readIOStat(dir string) *IOStat {
+	f, err := os.Open(filepath.Join(dir, "io.stat"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	stat := &IOStat{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, found := strings.Cut(field, "=")
+			if !found {
+				continue
+			}
+			val, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch key {
+			case "rbytes":
+				stat.ReadBytes += val
+			case "wbytes":
+				stat.WriteBytes += val
+			case "rios":
+				stat.ReadIOs += val
+			case "wios":
+				stat.WriteIOs += val
+			}
+		}
+	}
+	return stat
+}
+
+// PressureValues is a line of a pressure file, the averages are the
+// percentage of time stalled and the total is in usec
+type PressureValues struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
+
+// Pressure is the pressure stall information of a resource
+type Pressure struct {
+	Some PressureValues  `json:"some"`
+	Full *PressureValues `json:"full,omitempty"`
+}
+
+// readPressure reads a pressure file like cpu.pressure, which has the
+// lines 'some avg10=0.00 avg60=0.00 avg300=0.00 total=0' and 'full ...'
+func readPressure(dir, file string) *Pressure {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	pressure := &Pressure{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		values := PressureValues{}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "avg10":
+				values.Avg10, _ = strconv.ParseFloat(value, 64)
+			case "avg60":
+				values.Avg60, _ = strconv.ParseFloat(value, 64)
+			case "avg300":
+				values.Avg300, _ = strconv.ParseFloat(value, 64)
+			case "total":
+				values.Total, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			pressure.Some = values
+		case "full":
+			pressure.Full = &values
+		}
+	}
+	return pressure
+}
+
+type UnitResources struct {
+	Unit           string            `json:"unit"`
+	ControlGroup   string            `json:"control_group"`
+	CPUPercent     float64           `json:"cpu_percent"`
+	CPUUsageUSec   uint64            `json:"cpu_usage_usec"`
+	CPUUserUSec    uint64            `json:"cpu_user_usec"`
+	CPUSystemUSec  uint64            `json:"cpu_system_usec"`
+	CPUThrottled   uint64            `json:"cpu_throttled_usec,omitempty"`
+	MemoryCurrent  uint64            `json:"memory_current"`
+	MemoryPeak     uint64            `json:"memory_peak,omitempty"`
+	MemoryMax      uint64            `json:"memory_max,omitempty"`
+	MemoryStat     map[string]uint64 `json:"memory_stat,omitempty"`
+	TasksCurrent   uint64            `json:"tasks_current"`
+	TasksMax       uint64            `json:"tasks_max,omitempty"`
+	IO             *IOStat           `json:"io,omitempty"`
+	MemoryPressure *Pressure         `json:"memory_pressure,omitempty"`
+	CPUPressure    *Pressure         `json:"cpu_pressure,omitempty"`
+	IOPressure     *Pressure         `json:"io_pressure,omitempty"`
+}
+
+// readUnitResources reads the usage of the cgroup, the limits set to max
+// aren't reported. The details are memory.stat and the pressure files.
+func readUnitResources(unit, cgroup, dir string, details bool) UnitResources {
+	res := UnitResources{Unit: unit, ControlGroup: cgroup}
+	cpuStat := readCgroupKeyValues(dir, "cpu.stat")
+	res.CPUUsageUSec = cpuStat["usage_usec"]
+	res.CPUUserUSec = cpuStat["user_usec"]
+	res.CPUSystemUSec = cpuStat["system_usec"]
+	res.CPUThrottled = cpuStat["throttled_usec"]
+	res.MemoryCurrent, _ = readCgroupValue(dir, "memory.current")
+	res.MemoryPeak, _ = readCgroupValue(dir, "memory.peak")
+	if limit, ok := readCgroupValue(dir, "memory.max"); ok && limit != Infinity {
+		res.MemoryMax = limit
+	}
+	res.TasksCurrent, _ = readCgroupValue(dir, "pids.current")
+	if limit, ok := readCgroupValue(dir, "pids.max"); ok && limit != Infinity {
+		res.TasksMax = limit
+	}
+	res.IO = readIOStat(dir)
+	if details {
+		if memoryStat := readCgroupKeyValues(dir, "memory.stat"); memoryStat != nil {
+			res.MemoryStat = make(map[string]uint64)
+			for _, key := range memoryStatKeys {
+				if val, ok := memoryStat[key]; ok {
+					res.MemoryStat[key] = val
+				}
+			}
+		}
+		res.MemoryPressure = readPressure(dir, "memory.pressure")
+		res.CPUPressure = readPressure(dir, "cpu.pressure")
+		res.IOPressure = readPressure(dir, "io.pressure")
+	}
+	return res
+}
+
+// cpuPercent returns the CPU usage between two samples in percent of a
+// single CPU, so it may exceed 100 like in systemd-cgtop
+func cpuPercent(before, after uint64, interval time.Duration) float64 {
+	if after < before || interval <= 0 {
+		return 0
+	}
+	percent := float64(after-before) / float64(interval.Microseconds()) * 100
+	return math.Round(percent*10) / 10
+}
+
+// bytesPerSec returns the rate between two samples of a byte counter
+func bytesPerSec(before, after uint64, interval time.Duration) uint64 {
+	if after < before || interval <= 0 {
+		return 0
+	}
+	return uint64(float64(after-before) / interval.Seconds())
+}
+
+// ioRate returns the sampled read and write rate, which the top view is
+// sorted by like systemd-cgtop
+func ioRate(res UnitResources) uint64 {
+	if res.IO == nil {
+		return 0
+	}
+	return res.IO.ReadBytesPerSec + res.IO.WriteBytesPerSec
+}
+
+// ValidResourceSorts returns the fields the top view can be sorted by
+func ValidResourceSorts() []string {
+	return []string{"cpu", "memory", "tasks", "io"}
+}
+
+type UnitResourcesParams struct {
+	Name     string `json:"name,omitempty" jsonschema:"Exact name of the unit, like foo.service. If not set the services using the most resources are listed."`
+	Top      int    `json:"top,omitempty" jsonschema:"Number of services to list if no name is given, defaults to 10."`
+	SortBy   string `json:"sort_by,omitempty" jsonschema:"Sort the services by cpu, memory, tasks or io. Defaults to cpu."`
+	Interval int    `json:"interval,omitempty" jsonschema:"Milliseconds to sample the CPU and IO usage, defaults to 500 and is at most 5000."`
+}
+
+// show the resource usage of units from their control groups
+func (conn *Connection) UnitResources(ctx context.Context, req *mcp.CallToolRequest, params *UnitResourcesParams) (*mcp.CallToolResult, any, error) {
+	sortBy := params.SortBy
+	if sortBy == "" {
+		sortBy = "cpu"
+	}
+	if !slices.Contains(ValidResourceSorts(), sortBy) {
+		return nil, nil, fmt.Errorf("invalid sort %s, valid values are: %v", sortBy, ValidResourceSorts())
+	}
+	interval := time.Duration(params.Interval) * time.Millisecond
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	interval = min(interval, MaxSampleInterval)
+	top := params.Top
+	if top <= 0 {
+		top = 10
+	}
+	type unitCgroup struct {
+		unit, cgroup, dir string
+	}
+	cgroups := []unitCgroup{}
+	if params.Name != "" {
+		cgroup, dir, err := conn.unitCgroupDir(ctx, params.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return nil, nil, fmt.Errorf("couldn't read control group of %s: %w", params.Name, err)
+		}
+		cgroups = append(cgroups, unitCgroup{unit: params.Name, cgroup: cgroup, dir: dir})
+	} else {
+		units, err := conn.dbus.ListUnitsByPatternsContext(ctx, []string{"active"}, []string{"*.service"})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, unit := range units {
+			cgroup, dir, err := conn.unitCgroupDir(ctx, unit.Name)
+			if err != nil {
+				continue
+			}
+			cgroups = append(cgroups, unitCgroup{unit: unit.Name, cgroup: cgroup, dir: dir})
+		}
+	}
+	// sample the CPU and IO usage
+	before := make([]uint64, len(cgroups))
+	ioBefore := make([]*IOStat, len(cgroups))
+	for i, cg := range cgroups {
+		before[i] = readCgroupKeyValues(cg.dir, "cpu.stat")["usage_usec"]
+		ioBefore[i] = readIOStat(cg.dir)
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-time.After(interval):
+	}
+	elapsed := time.Since(start)
+	resources := []UnitResources{}
+	for i, cg := range cgroups {
+		res := readUnitResources(cg.unit, cg.cgroup, cg.dir, params.Name != "")
+		res.CPUPercent = cpuPercent(before[i], res.CPUUsageUSec, elapsed)
+		if res.IO != nil && ioBefore[i] != nil {
+			res.IO.ReadBytesPerSec = bytesPerSec(ioBefore[i].ReadBytes, res.IO.ReadBytes, elapsed)
+			res.IO.WriteBytesPerSec = bytesPerSec(ioBefore[i].WriteBytes, res.IO.WriteBytes, elapsed)
+		}
+		resources = append(resources, res)
+	}
+	slices.SortStableFunc(resources, func(a, b UnitResources) int {
+		var c int
+		switch sortBy {
+		case "cpu":
+			c = cmp.Compare(b.CPUPercent, a.CPUPercent)
+		case "memory":
+			c = cmp.Compare(b.MemoryCurrent, a.MemoryCurrent)
+		case "tasks":
+			c = cmp.Compare(b.TasksCurrent, a.TasksCurrent)
+		case "io":
+			c = cmp.Compare(ioRate(b), ioRate(a))
+		}
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a.Unit, b.Unit)
+	})
+	if len(resources) > top {
+		resources = resources[:top]
+	}
+	txtContentList := []mcp.Content{}
+	for _, res := range resources {
+		jsonByte, err := json.Marshal(res)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	if len(txtContentList) == 0 {
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: "no services with control group found",
+		})
+	}
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+	}, nil, nil
+}
@@ -0,0 +1,111 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCgroup creates the files of a cgroup below the root
+func writeCgroup(t *testing.T, root, cgroup string, files map[string]string) {
+	dir := filepath.Join(root, cgroup)
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func TestReadUnitResources(t *testing.T) {
+	root := t.TempDir()
+	writeCgroup(t, root, "system.slice/foo.service", map[string]string{
+		"cpu.stat":        "usage_usec 1500\nuser_usec 1000\nsystem_usec 500\nnr_periods 0\nthrottled_usec 0\n",
+		"memory.current":  "4096\n",
+		"memory.peak":     "8192\n",
+		"memory.max":      "max\n",
+		"memory.stat":     "anon 1024\nfile 2048\nunknown 1\n",
+		"pids.current":    "3\n",
+		"pids.max":        "100\n",
+		"io.stat":         "8:0 rbytes=10 wbytes=20 rios=1 wios=2 dbytes=0 dios=0\n\n259:0 rbytes=5 wbytes=5 rios=1 wios=1 dbytes=0 dios=0\n",
+		"memory.pressure": "some avg10=1.50 avg60=0.20 avg300=0.00 total=1234\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=12\n",
+		"cpu.pressure":    "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+	})
+	res := readUnitResources("foo.service", "/system.slice/foo.service", filepath.Join(root, "system.slice/foo.service"), true)
+	assert.Equal(t, UnitResources{
+		Unit:          "foo.service",
+		ControlGroup:  "/system.slice/foo.service",
+		CPUUsageUSec:  1500,
+		CPUUserUSec:   1000,
+		CPUSystemUSec: 500,
+		MemoryCurrent: 4096,
+		MemoryPeak:    8192,
+		MemoryStat:    map[string]uint64{"anon": 1024, "file": 2048},
+		TasksCurrent:  3,
+		TasksMax:      100,
+		IO:            &IOStat{ReadBytes: 15, WriteBytes: 25, ReadIOs: 2, WriteIOs: 3},
+		MemoryPressure: &Pressure{
+			Some: PressureValues{Avg10: 1.5, Avg60: 0.2, Total: 1234},
+			Full: &PressureValues{Total: 12},
+		},
+		CPUPressure: &Pressure{},
+	}, res)
+
+	assert.Equal(t, 50.0, cpuPercent(1000, 251000, 500*time.Millisecond))
+	assert.Equal(t, 0.0, cpuPercent(1000, 500, time.Second))
+	assert.Equal(t, uint64(2000), bytesPerSec(1000, 2000, 500*time.Millisecond))
+	assert.Equal(t, uint64(0), bytesPerSec(1000, 500, time.Second))
+}
+
+func TestUnitResourcesTop(t *testing.T) {
+	root := t.TempDir()
+	oldRoot := cgroupRoot
+	cgroupRoot = root
+	defer func() { cgroupRoot = oldRoot }()
+	writeCgroup(t, root, "system.slice/small.service", map[string]string{"memory.current": "10\n", "pids.current": "5\n"})
+	writeCgroup(t, root, "system.slice/big.service", map[string]string{"memory.current": "100\n", "pids.current": "1\n", "io.stat": "8:0 rbytes=1000000 wbytes=1000000\n"})
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			listUnitsByPatterns: func(patterns, states []string) ([]dbus.UnitStatus, error) {
+				return []dbus.UnitStatus{{Name: "small.service"}, {Name: "big.service"}, {Name: "stopped.service"}}, nil
+			},
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				if unitName == "stopped.service" {
+					return map[string]interface{}{"ControlGroup": ""}, nil
+				}
+				return map[string]interface{}{"ControlGroup": "/system.slice/" + unitName}, nil
+			},
+		},
+	}
+	units := func(params *UnitResourcesParams) []string {
+		res, _, err := conn.UnitResources(context.Background(), nil, params)
+		assert.NoError(t, err)
+		ret := []string{}
+		for _, content := range res.Content {
+			var resources UnitResources
+			assert.NoError(t, json.Unmarshal([]byte(content.(*mcp.TextContent).Text), &resources))
+			assert.Nil(t, resources.MemoryStat)
+			ret = append(ret, resources.Unit)
+		}
+		return ret
+	}
+	assert.Equal(t, []string{"big.service", "small.service"}, units(&UnitResourcesParams{SortBy: "memory", Interval: 1}))
+	assert.Equal(t, []string{"small.service"}, units(&UnitResourcesParams{SortBy: "tasks", Top: 1, Interval: 1}))
+	// the IO is sorted by the rate, a unit which did a lot of IO in the past
+	// but none now isn't first
+	writeCgroup(t, root, "system.slice/a.service", map[string]string{"io.stat": "8:0 rbytes=0 wbytes=0\n"})
+	conn.dbus.(*mockDbusConnection).listUnitsByPatterns = func(patterns, states []string) ([]dbus.UnitStatus, error) {
+		return []dbus.UnitStatus{{Name: "big.service"}, {Name: "a.service"}}, nil
+	}
+	assert.Equal(t, []string{"a.service", "big.service"}, units(&UnitResourcesParams{SortBy: "io", Interval: 1}))
+
+	_, _, err := conn.UnitResources(context.Background(), nil, &UnitResourcesParams{SortBy: "disk"})
+	assert.Error(t, err)
+	_, _, err = conn.UnitResources(context.Background(), nil, &UnitResourcesParams{Name: "stopped.service"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,198 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// systemCleanDirs are the base directories of the directories of the system
+// manager, see systemd.exec(5)
+var systemCleanDirs = map[string]string{
+	"configuration": "/etc",
+	"state":         "/var/lib",
+	"cache":         "/var/cache",
+	"logs":          "/var/log",
+	"runtime":       "/run",
+}
+
+// cleanProperties are the properties with the directories of each kind
+var cleanProperties = map[string]string{
+	"configuration": "ConfigurationDirectory",
+	"state":         "StateDirectory",
+	"cache":         "CacheDirectory",
+	"logs":          "LogsDirectory",
+	"runtime":       "RuntimeDirectory",
+}
+
+// ValidCleanKinds returns the kinds of directories which can be removed
+func ValidCleanKinds() []string {
+	return []string{"configuration", "state", "cache", "logs", "runtime", "all"}
+}
+
+// cleanBaseDir returns the base directory of the kind, for the user manager
+// these are the XDG directories
+func (conn *Connection) cleanBaseDir(kind string) (string, error) {
+	if !conn.user {
+		return systemCleanDirs[kind], nil
+	}
+	stateDir := func() (string, error) {
+		if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+			return dir, nil
+		}
+		home, err := os.UserHomeDir()
+		return filepath.Join(home, ".local/state"), err
+	}
+	switch kind {
+	case "configuration":
+		return os.UserConfigDir()
+	case "state":
+		return stateDir()
+	case "cache":
+		return os.UserCacheDir()
+	case "logs":
+		dir, err := stateDir()
+		return filepath.Join(dir, "log"), err
+	case "runtime":
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			return dir, nil
+		}
+		return "", fmt.Errorf("XDG_RUNTIME_DIR not set")
+	}
+	return "", fmt.Errorf("invalid kind: %s", kind)
+}
+
+// dirSize returns the size of the files below the directory, a symlink to
+// the directory like for DynamicUser is followed
+func dirSize(path string) (uint64, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return 0, err
+	}
+	var size uint64
+	err = filepath.WalkDir(resolved, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err == nil {
+				size += uint64(info.Size())
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+type CleanUnitParams struct {
+	Name    string   `json:"name" jsonschema:"Exact name of the unit, like foo.service."`
+	What    []string `json:"what,omitempty" jsonschema:"Kinds of directories to remove: configuration, state, cache, logs, runtime or all. Defaults to cache and runtime."`
+	Confirm bool     `json:"confirm,omitempty" jsonschema:"Remove the directories. Without it the directories which would be removed are only listed."`
+}
+
+type CleanDirectory struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	Size   uint64 `json:"size"`
+	Error  string `json:"error,omitempty"`
+}
+
+type CleanResult struct {
+	Unit        string           `json:"unit"`
+	What        []string         `json:"what"`
+	Directories []CleanDirectory `json:"directories"`
+	TotalSize   uint64           `json:"total_size"`
+	Removed     bool             `json:"removed"`
+	Note        string           `json:"note,omitempty"`
+}
+
+// remove the state, cache, logs, runtime or configuration directories of a
+// stopped unit
+func (conn *Connection) CleanUnit(ctx context.Context, req *mcp.CallToolRequest, params *CleanUnitParams) (*mcp.CallToolResult, any, error) {
+	if params.Name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	what := params.What
+	if len(what) == 0 {
+		what = []string{"cache", "runtime"}
+	}
+	kinds := []string{}
+	for _, kind := range what {
+		if !slices.Contains(ValidCleanKinds(), kind) {
+			return nil, nil, fmt.Errorf("invalid kind %s, valid kinds are: %v", kind, ValidCleanKinds())
+		}
+		if kind == "all" {
+			kinds = ValidCleanKinds()[:len(ValidCleanKinds())-1]
+			break
+		}
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, params.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if activeState, _ := props["ActiveState"].(string); activeState != "inactive" && activeState != "failed" {
+		return nil, nil, fmt.Errorf("%s is %s, stop it with stop_unit before cleaning it", params.Name, activeState)
+	}
+	result := CleanResult{
+		Unit:        params.Name,
+		What:        what,
+		Directories: []CleanDirectory{},
+	}
+	for _, kind := range kinds {
+		dirs := stringSlice(props, cleanProperties[kind])
+		if len(dirs) == 0 {
+			continue
+		}
+		base, err := conn.cleanBaseDir(kind)
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't get %s directory: %w", kind, err)
+		}
+		for _, dir := range dirs {
+			cleanDir := CleanDirectory{Kind: kind, Path: filepath.Join(base, dir)}
+			size, err := dirSize(cleanDir.Path)
+			switch {
+			case err == nil:
+				cleanDir.Exists = true
+				cleanDir.Size = size
+			case !os.IsNotExist(err):
+				cleanDir.Exists = true
+				cleanDir.Error = err.Error()
+			}
+			result.TotalSize += cleanDir.Size
+			result.Directories = append(result.Directories, cleanDir)
+		}
+	}
+	switch {
+	case !slices.ContainsFunc(result.Directories, func(d CleanDirectory) bool { return d.Exists }):
+		result.Note = "nothing to clean"
+	case !params.Confirm:
+		result.Note = "the directories weren't removed, call again with confirm to remove them"
+	default:
+		if err := conn.dbus.CleanUnitContext(ctx, params.Name, what); err != nil {
+			return nil, nil, fmt.Errorf("couldn't clean %s: %w", params.Name, err)
+		}
+		result.Removed = true
+	}
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,83 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanUnit(t *testing.T) {
+	root := t.TempDir()
+	oldDirs := maps.Clone(systemCleanDirs)
+	for kind := range systemCleanDirs {
+		systemCleanDirs[kind] = filepath.Join(root, kind)
+	}
+	defer func() { systemCleanDirs = oldDirs }()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "cache/foo/sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "cache/foo/a"), make([]byte, 100), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "cache/foo/sub/b"), make([]byte, 20), 0o644))
+	// DynamicUser links the directory to the private one
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "state/private/foo"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "state/private/foo/db"), make([]byte, 5), 0o644))
+	assert.NoError(t, os.Symlink("private/foo", filepath.Join(root, "state/foo")))
+
+	activeState := "inactive"
+	cleaned := [][]string{}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"ActiveState":      activeState,
+					"CacheDirectory":   []string{"foo"},
+					"StateDirectory":   []string{"foo"},
+					"RuntimeDirectory": []string{"foo"},
+				}, nil
+			},
+			cleanUnit: func(name string, mask []string) error {
+				cleaned = append(cleaned, mask)
+				return nil
+			},
+		},
+	}
+	clean := func(params *CleanUnitParams) CleanResult {
+		res, _, err := conn.CleanUnit(context.Background(), nil, params)
+		assert.NoError(t, err)
+		var result CleanResult
+		assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+		return result
+	}
+
+	result := clean(&CleanUnitParams{Name: "foo.service"})
+	assert.Equal(t, CleanResult{
+		Unit: "foo.service",
+		What: []string{"cache", "runtime"},
+		Directories: []CleanDirectory{
+			{Kind: "cache", Path: filepath.Join(root, "cache/foo"), Exists: true, Size: 120},
+			{Kind: "runtime", Path: filepath.Join(root, "runtime/foo")},
+		},
+		TotalSize: 120,
+		Note:      "the directories weren't removed, call again with confirm to remove them",
+	}, result)
+	assert.Empty(t, cleaned)
+
+	result = clean(&CleanUnitParams{Name: "foo.service", What: []string{"all"}, Confirm: true})
+	assert.True(t, result.Removed)
+	assert.Equal(t, uint64(125), result.TotalSize)
+	assert.Equal(t, [][]string{{"all"}}, cleaned)
+
+	result = clean(&CleanUnitParams{Name: "foo.service", What: []string{"logs"}, Confirm: true})
+	assert.False(t, result.Removed)
+	assert.Equal(t, "nothing to clean", result.Note)
+
+	_, _, err := conn.CleanUnit(context.Background(), nil, &CleanUnitParams{Name: "foo.service", What: []string{"everything"}})
+	assert.Error(t, err)
+	activeState = "active"
+	_, _, err = conn.CleanUnit(context.Background(), nil, &CleanUnitParams{Name: "foo.service"})
+	assert.ErrorContains(t, err, "stop it with stop_unit")
+}
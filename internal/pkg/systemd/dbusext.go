@@ -0,0 +1,136 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+)
+
+// systemdConn extends the connection of go-systemd with the methods of the
+// manager, which aren't wrapped by the library
+type systemdConn struct {
+	*dbus.Conn
+	bus     *godbus.Conn
+	manager godbus.BusObject
+}
+
+// newSystemdConn connects to the manager on the system or the session bus
+func newSystemdConn(ctx context.Context, user bool) (*systemdConn, error) {
+	conn := new(systemdConn)
+	var err error
+	if user {
+		conn.Conn, err = dbus.NewUserConnectionContext(ctx)
+	} else {
+		conn.Conn, err = dbus.NewSystemConnectionContext(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user {
+		conn.bus, err = godbus.ConnectSessionBus()
+	} else {
+		conn.bus, err = godbus.ConnectSystemBus()
+	}
+	if err != nil {
+		conn.Conn.Close()
+		return nil, err
+	}
+	conn.manager = conn.bus.Object("org.freedesktop.systemd1", "/org/freedesktop/systemd1")
+	return conn, nil
+}
+
+// Close both connections
+func (conn *systemdConn) Close() {
+	conn.Conn.Close()
+	conn.bus.Close()
+}
+
+// PresetUnitFilesWithModeContext enables or disables the unit files
+// according to the preset policy, the mode is one of full, enable-only or
+// disable-only
+func (conn *systemdConn) PresetUnitFilesWithModeContext(ctx context.Context, files []string, mode string, runtime bool, force bool) (bool, []dbus.EnableUnitFileChange, error) {
+	var carriesInstall bool
+	var changes []dbus.EnableUnitFileChange
+	err := conn.manager.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.PresetUnitFilesWithMode", 0, files, mode, runtime, force).Store(&carriesInstall, &changes)
+	return carriesInstall, changes, err
+}
+
+// PresetAllUnitFilesContext applies the preset policy to all unit files
+func (conn *systemdConn) PresetAllUnitFilesContext(ctx context.Context, mode string, runtime bool, force bool) ([]dbus.EnableUnitFileChange, error) {
+	var changes []dbus.EnableUnitFileChange
+	err := conn.manager.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.PresetAllUnitFiles", 0, mode, runtime, force).Store(&changes)
+	return changes, err
+}
+
+// AuxUnit is a transient unit which is created together with the main
+// transient unit, like the service of a timer
+type AuxUnit struct {
+	Name       string
+	Properties []dbus.Property
+}
+
+// StartTransientUnitAuxContext creates and starts a transient unit together
+// with auxiliary units, go-systemd always passes an empty list of these.
+// The job isn't waited for, its path is returned.
+func (conn *systemdConn) StartTransientUnitAuxContext(ctx context.Context, name string, mode string, properties []dbus.Property, aux []AuxUnit) (string, error) {
+	var job godbus.ObjectPath
+	err := conn.manager.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.StartTransientUnit", 0, name, mode, properties, aux).Store(&job)
+	return string(job), err
+}
+
+// GetManagerPropertiesContext returns all the properties of the manager,
+// like the boot timestamps and the system state
+func (conn *systemdConn) GetManagerPropertiesContext(ctx context.Context) (map[string]interface{}, error) {
+	var props map[string]godbus.Variant
+	err := conn.manager.CallWithContext(ctx, "org.freedesktop.DBus.Properties.GetAll", 0, "org.freedesktop.systemd1.Manager").Store(&props)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(props))
+	for key, value := range props {
+		out[key] = value.Value()
+	}
+	return out, nil
+}
+
+// CleanUnitContext removes the configuration, state, cache, logs or runtime
+// directories of the unit, the mask lists which of them
+func (conn *systemdConn) CleanUnitContext(ctx context.Context, name string, mask []string) error {
+	return conn.manager.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.CleanUnit", 0, name, mask).Store()
+}
+
+// GetUnitFileStateContext returns the state of the unit file like enabled
+// or static, unlike the properties of the unit this doesn't load the unit
+func (conn *systemdConn) GetUnitFileStateContext(ctx context.Context, name string) (string, error) {
+	var state string
+	err := conn.manager.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetUnitFileState", 0, name).Store(&state)
+	return state, err
+}
+
+// GetDefaultTargetContext returns the name of the default target
+func (conn *systemdConn) GetDefaultTargetContext(ctx context.Context) (string, error) {
+	var name string
+	err := conn.manager.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.GetDefaultTarget", 0).Store(&name)
+	return name, err
+}
+
+// SetDefaultTargetContext links default.target to the given target
+func (conn *systemdConn) SetDefaultTargetContext(ctx context.Context, name string, force bool) ([]dbus.EnableUnitFileChange, error) {
+	var changes []dbus.EnableUnitFileChange
+	err := conn.manager.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.SetDefaultTarget", 0, name, force).Store(&changes)
+	return changes, err
+}
+
+// ReexecuteContext serializes the state of the manager and executes it
+// again. The manager may not reply before it's executed, which isn't an
+// error.
+func (conn *systemdConn) ReexecuteContext(ctx context.Context) error {
+	err := conn.manager.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Reexecute", 0).Store()
+	var dbusErr godbus.Error
+	if errors.As(err, &dbusErr) && dbusErr.Name == "org.freedesktop.DBus.Error.NoReply" {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,209 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ValidDependencyTypes returns the dependency types which can be requested
+func ValidDependencyTypes() []string {
+	return []string{"Requires", "Wants", "BindsTo", "PartOf", "After", "Before", "Conflicts"}
+}
+
+// reverseDependency maps the dependency types to the property which holds
+// the units depending on the unit, e.g. if a has Requires=b, b has
+// RequiredBy=a, and if a has After=b, b has Before=a
+var reverseDependency = map[string]string{
+	"Requires":  "RequiredBy",
+	"Wants":     "WantedBy",
+	"BindsTo":   "BoundBy",
+	"PartOf":    "ConsistsOf",
+	"After":     "Before",
+	"Before":    "After",
+	"Conflicts": "ConflictedBy",
+}
+
+// requirementDependency are the dependency types which are followed when
+// walking the graph, the ordering and conflicts are only reported
+func requirementDependency(depType string) bool {
+	return slices.Contains([]string{"Requires", "Wants", "BindsTo", "PartOf"}, depType)
+}
+
+const MaxDependencyDepth uint = 10
+
+type DependencyParams struct {
+	Name    string   `json:"name" jsonschema:"Exact name of the unit for which the dependencies are listed."`
+	Reverse bool     `json:"reverse,omitempty" jsonschema:"List the units which depend on the given unit instead of its dependencies. Use this to see which units are affected when the unit is stopped."`
+	Depth   uint     `json:"depth,omitempty" jsonschema:"Depth of the recursion, defaults to 3. Only the dependencies Requires, Wants, BindsTo and PartOf are followed."`
+	Types   []string `json:"types,omitempty" jsonschema:"Dependency types to list, defaults to all of Requires, Wants, BindsTo, PartOf, After, Before and Conflicts."`
+	Format  string   `json:"format,omitempty" jsonschema:"Output format, either 'json' (default) or 'dot' for a Graphviz graph."`
+}
+
+type DependencyNode struct {
+	Name        string `json:"name"`
+	Depth       uint   `json:"depth"`
+	LoadState   string `json:"load_state,omitempty"`
+	ActiveState string `json:"active_state,omitempty"`
+	SubState    string `json:"sub_state,omitempty"`
+}
+
+// DependencyEdge is always in the direction of the dependency, so for the
+// reverse dependencies From is the depending unit
+type DependencyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+type DependencyGraph struct {
+	Root    string           `json:"root"`
+	Reverse bool             `json:"reverse"`
+	Depth   uint             `json:"depth"`
+	Nodes   []DependencyNode `json:"nodes"`
+	Edges   []DependencyEdge `json:"edges"`
+}
+
+// dependencyColors are the colors used by systemd-analyze dot
+var dependencyColors = map[string]string{
+	"Requires":  "black",
+	"BindsTo":   "black",
+	"PartOf":    "black",
+	"Wants":     "grey66",
+	"Conflicts": "red",
+	"After":     "green",
+	"Before":    "green",
+}
+
+// Dot returns the graph in the Graphviz format
+func (graph *DependencyGraph) Dot() string {
+	var bld strings.Builder
+	fmt.Fprintf(&bld, "digraph %q {\n", graph.Root)
+	for _, node := range graph.Nodes {
+		if node.ActiveState != "" {
+			fmt.Fprintf(&bld, "\t%q [tooltip=%q];\n", node.Name, node.ActiveState+"/"+node.SubState)
+		}
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&bld, "\t%q -> %q [label=%q, color=%q];\n", edge.From, edge.To, edge.Type, dependencyColors[edge.Type])
+	}
+	bld.WriteString("}\n")
+	return bld.String()
+}
+
+// stringSlice returns the property as string slice
+func stringSlice(props map[string]interface{}, key string) []string {
+	if lst, ok := props[key].([]string); ok {
+		return lst
+	}
+	return nil
+}
+
+// dependencyGraph walks the dependencies of the unit breadth first up to
+// the given depth
+func (conn *Connection) dependencyGraph(ctx context.Context, name string, reverse bool, depth uint, types []string) (*DependencyGraph, error) {
+	graph := &DependencyGraph{
+		Root:    name,
+		Reverse: reverse,
+		Depth:   depth,
+		Nodes:   []DependencyNode{},
+		Edges:   []DependencyEdge{},
+	}
+	// index of the node in graph.Nodes, nodes which are only reached by
+	// ordering or conflicts aren't expanded
+	index := map[string]int{}
+	queued := map[string]bool{name: true}
+	queue := []DependencyNode{{Name: name}}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		props, err := conn.dbus.GetAllPropertiesContext(ctx, node.Name)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get properties of %s: %w", node.Name, err)
+		}
+		node.LoadState, _ = props["LoadState"].(string)
+		node.ActiveState, _ = props["ActiveState"].(string)
+		node.SubState, _ = props["SubState"].(string)
+		if i, ok := index[node.Name]; ok {
+			graph.Nodes[i] = node
+		} else {
+			index[node.Name] = len(graph.Nodes)
+			graph.Nodes = append(graph.Nodes, node)
+		}
+		if node.Depth >= depth {
+			continue
+		}
+		for _, depType := range types {
+			prop := depType
+			if reverse {
+				prop = reverseDependency[depType]
+			}
+			for _, other := range stringSlice(props, prop) {
+				edge := DependencyEdge{From: node.Name, To: other, Type: depType}
+				if reverse {
+					edge = DependencyEdge{From: other, To: node.Name, Type: depType}
+				}
+				graph.Edges = append(graph.Edges, edge)
+				if requirementDependency(depType) && !queued[other] {
+					queued[other] = true
+					queue = append(queue, DependencyNode{Name: other, Depth: node.Depth + 1})
+				} else if _, ok := index[other]; !ok && !queued[other] {
+					index[other] = len(graph.Nodes)
+					graph.Nodes = append(graph.Nodes, DependencyNode{Name: other, Depth: node.Depth + 1})
+				}
+			}
+		}
+	}
+	return graph, nil
+}
+
+// list the dependencies or reverse dependencies of a unit
+func (conn *Connection) ListDependencies(ctx context.Context, req *mcp.CallToolRequest, params *DependencyParams) (*mcp.CallToolResult, any, error) {
+	if params.Name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	depth := params.Depth
+	if depth == 0 {
+		depth = 3
+	}
+	if depth > MaxDependencyDepth {
+		return nil, nil, fmt.Errorf("depth %d is larger than the maximal depth of %d", depth, MaxDependencyDepth)
+	}
+	types := params.Types
+	if len(types) == 0 {
+		types = ValidDependencyTypes()
+	}
+	for _, depType := range types {
+		if !slices.Contains(ValidDependencyTypes(), depType) {
+			return nil, nil, fmt.Errorf("invalid dependency type %s, valid types are: %v", depType, ValidDependencyTypes())
+		}
+	}
+	if params.Format != "" && params.Format != "json" && params.Format != "dot" {
+		return nil, nil, fmt.Errorf("invalid format %s, valid formats are json and dot", params.Format)
+	}
+	graph, err := conn.dependencyGraph(ctx, params.Name, params.Reverse, depth, types)
+	if err != nil {
+		return nil, nil, err
+	}
+	var text string
+	if params.Format == "dot" {
+		text = graph.Dot()
+	} else {
+		jsonByte, err := json.Marshal(graph)
+		if err != nil {
+			return nil, nil, err
+		}
+		text = string(jsonByte)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: text,
+			},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,70 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dependencyProps() map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		"a.service": {
+			"ActiveState": "active",
+			"Requires":    []string{"b.service"},
+			"After":       []string{"b.service", "basic.target"},
+		},
+		"b.service": {
+			"ActiveState": "active",
+			"Wants":       []string{"c.service"},
+			"RequiredBy":  []string{"a.service"},
+			"Before":      []string{"a.service"},
+		},
+		"c.service": {
+			"ActiveState": "inactive",
+			"WantedBy":    []string{"b.service"},
+		},
+	}
+}
+
+func TestDependencyGraph(t *testing.T) {
+	props := dependencyProps()
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				if p, ok := props[unitName]; ok {
+					return p, nil
+				}
+				return nil, fmt.Errorf("unit %s not found", unitName)
+			},
+		},
+	}
+
+	graph, err := conn.dependencyGraph(context.Background(), "a.service", false, 3, ValidDependencyTypes())
+	assert.NoError(t, err)
+	assert.Equal(t, []DependencyNode{
+		{Name: "a.service", Depth: 0, ActiveState: "active"},
+		{Name: "basic.target", Depth: 1},
+		{Name: "b.service", Depth: 1, ActiveState: "active"},
+		{Name: "c.service", Depth: 2, ActiveState: "inactive"},
+	}, graph.Nodes)
+	assert.Equal(t, []DependencyEdge{
+		{From: "a.service", To: "b.service", Type: "Requires"},
+		{From: "a.service", To: "b.service", Type: "After"},
+		{From: "a.service", To: "basic.target", Type: "After"},
+		{From: "b.service", To: "c.service", Type: "Wants"},
+		{From: "b.service", To: "a.service", Type: "Before"},
+	}, graph.Edges)
+
+	graph, err = conn.dependencyGraph(context.Background(), "c.service", true, 1, []string{"Wants", "Requires"})
+	assert.NoError(t, err)
+	assert.Equal(t, []DependencyNode{
+		{Name: "c.service", Depth: 0, ActiveState: "inactive"},
+		{Name: "b.service", Depth: 1, ActiveState: "active"},
+	}, graph.Nodes)
+	assert.Equal(t, []DependencyEdge{
+		{From: "b.service", To: "c.service", Type: "Wants"},
+	}, graph.Edges)
+	assert.Contains(t, graph.Dot(), `"b.service" -> "c.service" [label="Wants", color="grey66"];`)
+}
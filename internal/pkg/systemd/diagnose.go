@@ -0,0 +1,179 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/util"
+)
+
+// UnitLog gives access to the journal entries of a unit
+type UnitLog interface {
+	// InvocationErrors returns the last count error lines of the given
+	// invocation, which may also be 'latest' or 'previous'
+	InvocationErrors(ctx context.Context, unit, invocation string, count int) ([]string, error)
+	// InvocationOutput returns the last count messages written by the
+	// processes of the invocation
+	InvocationOutput(ctx context.Context, unit, invocation string, count int) ([]string, error)
+	// Coredumps returns the COREDUMP_ fields of the last count coredumps
+	Coredumps(ctx context.Context, unit string, count int) ([]map[string]string, error)
+}
+
+// SetUnitLog sets the journal which is used for the diagnosis of units
+func (conn *Connection) SetUnitLog(log UnitLog) {
+	conn.unitLog = log
+}
+
+type DiagnoseParams struct {
+	Name  string `json:"name" jsonschema:"Exact name of the unit to diagnose."`
+	Lines int    `json:"lines,omitempty" jsonschema:"Number of error log lines of the latest run to include, defaults to 10."`
+}
+
+type StartLimit struct {
+	Hit          bool   `json:"hit"`
+	Burst        uint32 `json:"burst"`
+	IntervalUSec uint64 `json:"interval_usec"`
+	Action       string `json:"action,omitempty"`
+}
+
+type ConditionFailure struct {
+	Type      string `json:"type"`
+	Parameter string `json:"parameter"`
+	Negate    bool   `json:"negate"`
+	Trigger   bool   `json:"trigger"`
+	Assert    bool   `json:"assert"`
+}
+
+type Diagnosis struct {
+	Unit           string              `json:"unit"`
+	Description    string              `json:"description,omitempty"`
+	LoadState      string              `json:"load_state"`
+	ActiveState    string              `json:"active_state"`
+	SubState       string              `json:"sub_state"`
+	FreezerState   string              `json:"freezer_state,omitempty"`
+	UnitFileState  string              `json:"unit_file_state,omitempty"`
+	Result         string              `json:"result,omitempty"`
+	InvocationID   string              `json:"invocation_id,omitempty"`
+	MainPID        uint32              `json:"main_pid,omitempty"`
+	ExecMainCode   string              `json:"exec_main_code,omitempty"`
+	ExecMainStatus int32               `json:"exec_main_status"`
+	ExitStatus     string              `json:"exit_status,omitempty"`
+	NRestarts      uint32              `json:"n_restarts"`
+	StartLimit     StartLimit          `json:"start_limit"`
+	ConditionMet   bool                `json:"condition_result"`
+	AssertMet      bool                `json:"assert_result"`
+	Conditions     []ConditionFailure  `json:"failed_conditions,omitempty"`
+	Errors         []string            `json:"errors,omitempty"`
+	Coredumps      []map[string]string `json:"coredumps,omitempty"`
+	Notes          []string            `json:"notes,omitempty"`
+}
+
+// failedConditions returns the failed conditions or asserts of the
+// property, which has the signature a(sbbsi) with type, trigger, negate,
+// parameter and state, where a negative state is a failure
+func failedConditions(props map[string]interface{}, key string, assert bool) (ret []ConditionFailure) {
+	conds, ok := props[key].([][]interface{})
+	if !ok {
+		return nil
+	}
+	for _, cond := range conds {
+		if len(cond) != 5 {
+			continue
+		}
+		state, _ := cond[4].(int32)
+		if state >= 0 {
+			continue
+		}
+		failure := ConditionFailure{Assert: assert}
+		failure.Type, _ = cond[0].(string)
+		failure.Trigger, _ = cond[1].(bool)
+		failure.Negate, _ = cond[2].(bool)
+		failure.Parameter, _ = cond[3].(string)
+		ret = append(ret, failure)
+	}
+	return ret
+}
+
+// diagnosisFromProperties fills the diagnosis with the state of the unit
+func diagnosisFromProperties(props map[string]interface{}) *Diagnosis {
+	diag := &Diagnosis{}
+	diag.Unit, _ = props["Id"].(string)
+	diag.Description, _ = props["Description"].(string)
+	diag.LoadState, _ = props["LoadState"].(string)
+	diag.ActiveState, _ = props["ActiveState"].(string)
+	diag.SubState, _ = props["SubState"].(string)
+	// only a frozen or thawing unit is worth mentioning
+	if state, _ := props["FreezerState"].(string); state != "running" {
+		diag.FreezerState = state
+	}
+	diag.UnitFileState, _ = props["UnitFileState"].(string)
+	diag.Result, _ = props["Result"].(string)
+	if id, ok := props["InvocationID"].([]byte); ok && len(id) > 0 {
+		diag.InvocationID = fmt.Sprintf("%x", id)
+	}
+	diag.MainPID, _ = props["MainPID"].(uint32)
+	code, _ := props["ExecMainCode"].(int32)
+	diag.ExecMainCode = ExitCodeName(code)
+	diag.ExecMainStatus, _ = props["ExecMainStatus"].(int32)
+	diag.ExitStatus = DecodeExitStatus(code, diag.ExecMainStatus)
+	diag.NRestarts, _ = props["NRestarts"].(uint32)
+	diag.StartLimit.Hit = diag.Result == "start-limit-hit"
+	diag.StartLimit.Burst, _ = props["StartLimitBurst"].(uint32)
+	diag.StartLimit.IntervalUSec, _ = props["StartLimitIntervalUSec"].(uint64)
+	diag.StartLimit.Action, _ = props["StartLimitAction"].(string)
+	diag.ConditionMet, _ = props["ConditionResult"].(bool)
+	diag.AssertMet, _ = props["AssertResult"].(bool)
+	diag.Conditions = append(failedConditions(props, "Conditions", false), failedConditions(props, "Asserts", true)...)
+	return diag
+}
+
+// diagnose a unit by its state, exit status, conditions and logs
+func (conn *Connection) DiagnoseUnit(ctx context.Context, req *mcp.CallToolRequest, params *DiagnoseParams) (*mcp.CallToolResult, any, error) {
+	if params.Name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	lines := params.Lines
+	if lines <= 0 {
+		lines = 10
+	}
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, params.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	props = util.ClearMap(props)
+	diag := diagnosisFromProperties(props)
+	if diag.Unit == "" {
+		diag.Unit = params.Name
+	}
+	if diag.LoadState == "not-found" {
+		diag.Notes = append(diag.Notes, "unit file not found, check the name with list_unit_files")
+	}
+	if diag.StartLimit.Hit {
+		diag.Notes = append(diag.Notes, "the start rate limit was hit, reset the failed state with reset_failed before starting the unit again")
+	}
+	if conn.unitLog == nil {
+		diag.Notes = append(diag.Notes, "journal not available, no log entries included")
+	} else {
+		diag.Errors, err = conn.unitLog.InvocationErrors(ctx, diag.Unit, "latest", lines)
+		if err != nil {
+			diag.Notes = append(diag.Notes, fmt.Sprintf("couldn't get log entries: %s", err))
+		}
+		diag.Coredumps, err = conn.unitLog.Coredumps(ctx, diag.Unit, 1)
+		if err != nil {
+			diag.Notes = append(diag.Notes, fmt.Sprintf("couldn't get coredumps: %s", err))
+		}
+	}
+	jsonByte, err := json.Marshal(diag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,80 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockUnitLog struct {
+	errors    []string
+	output    []string
+	coredumps []map[string]string
+}
+
+func (m *mockUnitLog) InvocationErrors(ctx context.Context, unit, invocation string, count int) ([]string, error) {
+	return m.errors, nil
+}
+
+func (m *mockUnitLog) InvocationOutput(ctx context.Context, unit, invocation string, count int) ([]string, error) {
+	return m.output, nil
+}
+
+func (m *mockUnitLog) Coredumps(ctx context.Context, unit string, count int) ([]map[string]string, error) {
+	return m.coredumps, nil
+}
+
+func TestDiagnoseUnit(t *testing.T) {
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"Id":              unitName,
+					"LoadState":       "loaded",
+					"ActiveState":     "failed",
+					"SubState":        "failed",
+					"Result":          "start-limit-hit",
+					"InvocationID":    []byte{0xab, 0xcd},
+					"ExecMainCode":    int32(2),
+					"ExecMainStatus":  int32(11),
+					"NRestarts":       uint32(5),
+					"StartLimitBurst": uint32(5),
+					"ConditionResult": true,
+					"AssertResult":    false,
+					"Asserts": [][]interface{}{
+						{"AssertPathExists", false, false, "/etc/foo.conf", int32(-1)},
+						{"AssertPathExists", false, false, "/etc/bar.conf", int32(1)},
+					},
+				}, nil
+			},
+		},
+		unitLog: &mockUnitLog{
+			errors:    []string{"foo[1]: segfault"},
+			coredumps: []map[string]string{{"COREDUMP_SIGNAL_NAME": "SIGSEGV"}},
+		},
+	}
+	res, _, err := conn.DiagnoseUnit(context.Background(), nil, &DiagnoseParams{Name: "foo.service"})
+	assert.NoError(t, err)
+	var diag Diagnosis
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &diag))
+	assert.Equal(t, "foo.service", diag.Unit)
+	assert.Equal(t, "abcd", diag.InvocationID)
+	assert.Equal(t, "killed", diag.ExecMainCode)
+	assert.Equal(t, "SIGSEGV", diag.ExitStatus)
+	assert.Equal(t, uint32(5), diag.NRestarts)
+	assert.True(t, diag.StartLimit.Hit)
+	assert.Equal(t, []ConditionFailure{{Type: "AssertPathExists", Parameter: "/etc/foo.conf", Assert: true}}, diag.Conditions)
+	assert.Equal(t, []string{"foo[1]: segfault"}, diag.Errors)
+	assert.Len(t, diag.Coredumps, 1)
+}
+
+func TestDecodeExitStatus(t *testing.T) {
+	assert.Equal(t, "FAILURE", DecodeExitStatus(1, 1))
+	assert.Equal(t, "EXEC", DecodeExitStatus(1, 203))
+	assert.Equal(t, "SIGKILL", DecodeExitStatus(2, 9))
+	assert.Equal(t, "SIGABRT", DecodeExitStatus(3, 6))
+	assert.Equal(t, "", DecodeExitStatus(0, 0))
+}
@@ -0,0 +1,256 @@
+package systemd
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// directives of the [Unit] section, see systemd.unit(5)
+var unitDirectives = []string{
+	"Description", "Documentation", "Wants", "Requires", "Requisite", "BindsTo",
+	"PartOf", "Upholds", "Conflicts", "Before", "After", "OnFailure", "OnSuccess",
+	"PropagatesReloadTo", "ReloadPropagatedFrom", "PropagatesStopTo",
+	"StopPropagatedFrom", "JoinsNamespaceOf", "RequiresMountsFor",
+	"WantsMountsFor", "OnFailureJobMode", "IgnoreOnIsolate", "StopWhenUnneeded",
+	"RefuseManualStart", "RefuseManualStop", "AllowIsolate",
+	"DefaultDependencies", "SurviveFinalKillSignal", "CollectMode",
+	"FailureAction", "SuccessAction", "FailureActionExitStatus",
+	"SuccessActionExitStatus", "JobTimeoutSec", "JobRunningTimeoutSec",
+	"JobTimeoutAction", "JobTimeoutRebootArgument", "StartLimitIntervalSec",
+	"StartLimitBurst", "StartLimitAction", "RebootArgument", "SourcePath",
+	"ConditionArchitecture", "ConditionFirmware", "ConditionVirtualization",
+	"ConditionHost", "ConditionKernelCommandLine", "ConditionKernelVersion",
+	"ConditionCredential", "ConditionEnvironment", "ConditionSecurity",
+	"ConditionCapability", "ConditionACPower", "ConditionNeedsUpdate",
+	"ConditionFirstBoot", "ConditionPathExists", "ConditionPathExistsGlob",
+	"ConditionPathIsDirectory", "ConditionPathIsSymbolicLink",
+	"ConditionPathIsMountPoint", "ConditionPathIsReadWrite",
+	"ConditionPathIsEncrypted", "ConditionDirectoryNotEmpty",
+	"ConditionFileNotEmpty", "ConditionFileIsExecutable", "ConditionUser",
+	"ConditionGroup", "ConditionControlGroupController", "ConditionMemory",
+	"ConditionCPUs", "ConditionCPUFeature", "ConditionOSRelease",
+	"ConditionMemoryPressure", "ConditionCPUPressure", "ConditionIOPressure",
+	"AssertArchitecture", "AssertFirmware", "AssertVirtualization",
+	"AssertHost", "AssertKernelCommandLine", "AssertKernelVersion",
+	"AssertCredential", "AssertEnvironment", "AssertSecurity",
+	"AssertCapability", "AssertACPower", "AssertNeedsUpdate", "AssertFirstBoot",
+	"AssertPathExists", "AssertPathExistsGlob", "AssertPathIsDirectory",
+	"AssertPathIsSymbolicLink", "AssertPathIsMountPoint",
+	"AssertPathIsReadWrite", "AssertPathIsEncrypted", "AssertDirectoryNotEmpty",
+	"AssertFileNotEmpty", "AssertFileIsExecutable", "AssertUser", "AssertGroup",
+	"AssertControlGroupController", "AssertMemory", "AssertCPUs",
+	"AssertCPUFeature", "AssertOSRelease", "AssertMemoryPressure",
+	"AssertCPUPressure", "AssertIOPressure",
+}
+
+// directives of the [Install] section
+var installDirectives = []string{
+	"Alias", "WantedBy", "RequiredBy", "UpheldBy", "Also", "DefaultInstance",
+}
+
+// directives of systemd.exec(5) which are shared by services, sockets,
+// mounts and swaps
+var execDirectives = []string{
+	"ExecSearchPath", "WorkingDirectory", "RootDirectory", "RootImage",
+	"RootImageOptions", "RootEphemeral", "RootHash", "RootHashSignature",
+	"RootVerity", "RootImagePolicy", "MountImagePolicy",
+	"ExtensionImagePolicy", "MountAPIVFS", "ProtectProc", "ProcSubset",
+	"BindPaths", "BindReadOnlyPaths", "MountImages", "ExtensionImages",
+	"ExtensionDirectories", "User", "Group", "DynamicUser",
+	"SupplementaryGroups", "SetLoginEnvironment", "PAMName",
+	"CapabilityBoundingSet", "AmbientCapabilities", "NoNewPrivileges",
+	"SecureBits", "SELinuxContext", "AppArmorProfile", "SmackProcessLabel",
+	"LimitCPU", "LimitFSIZE", "LimitDATA", "LimitSTACK", "LimitCORE",
+	"LimitRSS", "LimitNOFILE", "LimitAS", "LimitNPROC", "LimitMEMLOCK",
+	"LimitLOCKS", "LimitSIGPENDING", "LimitMSGQUEUE", "LimitNICE",
+	"LimitRTPRIO", "LimitRTTIME", "UMask", "CoredumpFilter", "KeyringMode",
+	"OOMScoreAdjust", "TimerSlackNSec", "Personality", "IgnoreSIGPIPE", "Nice",
+	"CPUSchedulingPolicy", "CPUSchedulingPriority",
+	"CPUSchedulingResetOnFork", "CPUAffinity", "NUMAPolicy", "NUMAMask",
+	"IOSchedulingClass", "IOSchedulingPriority", "ProtectSystem",
+	"ProtectHome", "RuntimeDirectory", "StateDirectory", "CacheDirectory",
+	"LogsDirectory", "ConfigurationDirectory", "RuntimeDirectoryMode",
+	"StateDirectoryMode", "CacheDirectoryMode", "LogsDirectoryMode",
+	"ConfigurationDirectoryMode", "RuntimeDirectoryPreserve",
+	"TimeoutCleanSec", "ReadWritePaths", "ReadOnlyPaths", "InaccessiblePaths",
+	"ExecPaths", "NoExecPaths", "TemporaryFileSystem", "PrivateTmp",
+	"PrivateDevices", "PrivateNetwork", "NetworkNamespacePath",
+	"PrivateIPC", "IPCNamespacePath", "MemoryKSM", "PrivateUsers",
+	"ProtectHostname", "ProtectClock", "ProtectKernelTunables",
+	"ProtectKernelModules", "ProtectKernelLogs", "ProtectControlGroups",
+	"RestrictAddressFamilies", "RestrictFileSystems", "RestrictNamespaces",
+	"LockPersonality", "MemoryDenyWriteExecute", "RestrictRealtime",
+	"RestrictSUIDSGID", "RemoveIPC", "PrivateMounts", "MountFlags",
+	"SystemCallFilter", "SystemCallErrorNumber", "SystemCallArchitectures",
+	"SystemCallLog", "Environment", "EnvironmentFile", "PassEnvironment",
+	"UnsetEnvironment", "StandardInput", "StandardOutput", "StandardError",
+	"StandardInputText", "StandardInputData", "LogLevelMax", "LogExtraFields",
+	"LogRateLimitIntervalSec", "LogRateLimitBurst", "LogFilterPatterns",
+	"LogNamespace", "SyslogIdentifier", "SyslogFacility", "SyslogLevel",
+	"SyslogLevelPrefix", "TTYPath", "TTYReset", "TTYVHangup", "TTYRows",
+	"TTYColumns", "TTYVTDisallocate", "LoadCredential",
+	"LoadCredentialEncrypted", "ImportCredential", "SetCredential",
+	"SetCredentialEncrypted", "UtmpIdentifier", "UtmpMode",
+}
+
+// directives of systemd.kill(5)
+var killDirectives = []string{
+	"KillMode", "KillSignal", "RestartKillSignal", "SendSIGHUP",
+	"SendSIGKILL", "FinalKillSignal", "WatchdogSignal",
+}
+
+// directives of systemd.resource-control(5)
+var resourceControlDirectives = []string{
+	"CPUAccounting", "CPUWeight", "StartupCPUWeight", "CPUQuota",
+	"CPUQuotaPeriodSec", "AllowedCPUs", "StartupAllowedCPUs",
+	"AllowedMemoryNodes", "StartupAllowedMemoryNodes", "MemoryAccounting",
+	"MemoryMin", "MemoryLow", "StartupMemoryLow", "DefaultStartupMemoryLow",
+	"MemoryHigh", "StartupMemoryHigh", "MemoryMax", "StartupMemoryMax",
+	"MemorySwapMax", "StartupMemorySwapMax", "MemoryZSwapMax",
+	"StartupMemoryZSwapMax", "MemoryZSwapWriteback", "TasksAccounting",
+	"TasksMax", "IOAccounting", "IOWeight", "StartupIOWeight",
+	"IODeviceWeight", "IOReadBandwidthMax", "IOWriteBandwidthMax",
+	"IOReadIOPSMax", "IOWriteIOPSMax", "IODeviceLatencyTargetSec",
+	"IPAccounting", "IPAddressAllow", "IPAddressDeny", "SocketBindAllow",
+	"SocketBindDeny", "RestrictNetworkInterfaces", "NFTSet", "IPIngressFilterPath",
+	"IPEgressFilterPath", "BPFProgram", "DeviceAllow", "DevicePolicy", "Slice",
+	"Delegate", "DelegateSubgroup", "DisableControllers",
+	"ManagedOOMSwap", "ManagedOOMMemoryPressure",
+	"ManagedOOMMemoryPressureLimit", "ManagedOOMPreference",
+	"MemoryPressureWatch", "MemoryPressureThresholdSec",
+	"CoredumpReceive", "BlockIOAccounting", "BlockIOWeight",
+	"StartupBlockIOWeight", "BlockIODeviceWeight", "BlockIOReadBandwidth",
+	"BlockIOWriteBandwidth", "CPUShares", "StartupCPUShares", "MemoryLimit",
+}
+
+// directives of the [Service] section, see systemd.service(5)
+var serviceDirectives = []string{
+	"Type", "ExitType", "RemainAfterExit", "GuessMainPID", "PIDFile",
+	"BusName", "ExecStart", "ExecStartPre", "ExecStartPost", "ExecCondition",
+	"ExecReload", "ExecStop", "ExecStopPost", "RestartSec", "RestartSteps",
+	"RestartMaxDelaySec", "TimeoutStartSec", "TimeoutStopSec",
+	"TimeoutAbortSec", "TimeoutSec", "TimeoutStartFailureMode",
+	"TimeoutStopFailureMode", "RuntimeMaxSec", "RuntimeRandomizedExtraSec",
+	"WatchdogSec", "Restart", "RestartMode", "SuccessExitStatus",
+	"RestartPreventExitStatus", "RestartForceExitStatus", "RootDirectoryStartOnly",
+	"NonBlocking", "NotifyAccess", "Sockets", "FileDescriptorStoreMax",
+	"FileDescriptorStorePreserve", "USBFunctionDescriptors",
+	"USBFunctionStrings", "OOMPolicy", "OpenFile", "ReloadSignal",
+	"PermissionsStartOnly",
+}
+
+// directives of the [Socket] section, see systemd.socket(5)
+var socketDirectives = []string{
+	"ListenStream", "ListenDatagram", "ListenSequentialPacket", "ListenFIFO",
+	"ListenSpecial", "ListenNetlink", "ListenMessageQueue",
+	"ListenUSBFunction", "SocketProtocol", "BindIPv6Only", "Backlog",
+	"BindToDevice", "SocketUser", "SocketGroup", "SocketMode",
+	"DirectoryMode", "Accept", "Writable", "FlushPending", "MaxConnections",
+	"MaxConnectionsPerSource", "KeepAlive", "KeepAliveTimeSec",
+	"KeepAliveIntervalSec", "KeepAliveProbes", "NoDelay", "Priority",
+	"DeferAcceptSec", "ReceiveBuffer", "SendBuffer", "IPTOS", "IPTTL", "Mark",
+	"ReusePort", "SmackLabel", "SmackLabelIPIn", "SmackLabelIPOut",
+	"SELinuxContextFromNet", "PipeSize", "MessageQueueMaxMessages",
+	"MessageQueueMessageSize", "FreeBind", "Transparent", "Broadcast",
+	"PassCredentials", "PassSecurity", "PassPacketInfo", "Timestamping",
+	"TCPCongestion", "ExecStartPre", "ExecStartPost", "ExecStopPre",
+	"ExecStopPost", "TimeoutSec", "Service", "RemoveOnStop", "Symlinks",
+	"FileDescriptorName", "TriggerLimitIntervalSec", "TriggerLimitBurst",
+	"PollLimitIntervalSec", "PollLimitBurst",
+}
+
+// directives of the [Mount] section, see systemd.mount(5)
+var mountDirectives = []string{
+	"What", "Where", "Type", "Options", "SloppyOptions", "LazyUnmount",
+	"ReadWriteOnly", "ForceUnmount", "DirectoryMode", "TimeoutSec",
+}
+
+// directives of the [Automount] section, see systemd.automount(5)
+var automountDirectives = []string{
+	"Where", "ExtraOptions", "DirectoryMode", "TimeoutIdleSec",
+}
+
+// directives of the [Swap] section, see systemd.swap(5)
+var swapDirectives = []string{
+	"What", "Priority", "Options", "TimeoutSec",
+}
+
+// directives of the [Timer] section, see systemd.timer(5)
+var timerDirectives = []string{
+	"OnActiveSec", "OnBootSec", "OnStartupSec", "OnUnitActiveSec",
+	"OnUnitInactiveSec", "OnCalendar", "AccuracySec", "RandomizedDelaySec",
+	"FixedRandomDelay", "OnClockChange", "OnTimezoneChange", "Unit",
+	"Persistent", "WakeSystem", "RemainAfterElapse",
+}
+
+// directives of the [Path] section, see systemd.path(5)
+var pathDirectives = []string{
+	"PathExists", "PathExistsGlob", "PathChanged", "PathModified",
+	"DirectoryNotEmpty", "Unit", "MakeDirectory", "DirectoryMode",
+	"TriggerLimitIntervalSec", "TriggerLimitBurst",
+}
+
+// unitSections maps the unit type to the type specific section and its
+// directives
+var unitSections = map[string]map[string][]string{
+	"service":   {"Service": slices.Concat(serviceDirectives, execDirectives, killDirectives, resourceControlDirectives)},
+	"socket":    {"Socket": slices.Concat(socketDirectives, execDirectives, killDirectives, resourceControlDirectives)},
+	"mount":     {"Mount": slices.Concat(mountDirectives, execDirectives, killDirectives, resourceControlDirectives)},
+	"swap":      {"Swap": slices.Concat(swapDirectives, execDirectives, killDirectives, resourceControlDirectives)},
+	"automount": {"Automount": automountDirectives},
+	"timer":     {"Timer": timerDirectives},
+	"path":      {"Path": pathDirectives},
+	"slice":     {"Slice": resourceControlDirectives},
+	"scope":     {"Scope": slices.Concat([]string{"RuntimeMaxSec", "RuntimeRandomizedExtraSec", "OOMPolicy"}, killDirectives, resourceControlDirectives)},
+	"target":    {},
+	"device":    {},
+}
+
+// UnitType returns the type of the unit, which is the suffix of its name
+func UnitType(name string) string {
+	return strings.TrimPrefix(filepath.Ext(name), ".")
+}
+
+// KnownSections returns the sections which are valid for the given unit
+// type, or nil if the type isn't known
+func KnownSections(unitType string) []string {
+	typeSections, ok := unitSections[unitType]
+	if !ok {
+		return nil
+	}
+	sections := []string{"Unit", "Install"}
+	for section := range typeSections {
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// CheckDirective checks if the section and key are known for the unit
+// type. Sections and keys starting with 'X-' are extensions and always
+// accepted.
+func CheckDirective(unitType, section, key string) (knownSection, knownKey bool) {
+	if strings.HasPrefix(section, "X-") {
+		return true, true
+	}
+	var directives []string
+	switch section {
+	case "Unit":
+		directives = unitDirectives
+	case "Install":
+		directives = installDirectives
+	default:
+		typeSections, ok := unitSections[unitType]
+		if !ok {
+			return false, false
+		}
+		directives, ok = typeSections[section]
+		if !ok {
+			return false, false
+		}
+	}
+	if strings.HasPrefix(key, "X-") {
+		return true, true
+	}
+	return true, slices.Contains(directives, key)
+}
@@ -0,0 +1,98 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// freezePollInterval is the interval in which the freezer state is checked
+var freezePollInterval = 100 * time.Millisecond
+
+// freezeTimeOut is the time to wait for the freezer state to settle
+const freezeTimeOut = 3 * time.Second
+
+type FreezeParams struct {
+	Name string `json:"name" jsonschema:"Exact name of the unit, like foo.service."`
+}
+
+type FreezeResult struct {
+	Unit         string `json:"unit"`
+	ActiveState  string `json:"active_state"`
+	FreezerState string `json:"freezer_state"`
+	Note         string `json:"note,omitempty"`
+}
+
+// freezeUnit freezes or thaws the unit and waits until the freezer state
+// is frozen or running
+func (conn *Connection) freezeUnit(ctx context.Context, name string, freeze bool) (*mcp.CallToolResult, any, error) {
+	if name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	activeState, _ := props["ActiveState"].(string)
+	if freeze && activeState != "active" && activeState != "reloading" {
+		return nil, nil, fmt.Errorf("only active units can be frozen, %s is %s", name, activeState)
+	}
+	want := "running"
+	if freeze {
+		err = conn.dbus.FreezeUnit(ctx, name)
+		want = "frozen"
+	} else {
+		err = conn.dbus.ThawUnit(ctx, name)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't change freezer state of %s, which needs the unified cgroup hierarchy: %w", name, err)
+	}
+	result := FreezeResult{Unit: name}
+	deadline := time.Now().Add(freezeTimeOut)
+	for {
+		props, err = conn.dbus.GetAllPropertiesContext(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.ActiveState, _ = props["ActiveState"].(string)
+		result.FreezerState, _ = props["FreezerState"].(string)
+		if result.FreezerState == want || !time.Now().Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(freezePollInterval):
+		}
+	}
+	switch {
+	case result.FreezerState != want:
+		result.Note = fmt.Sprintf("%s is still %s after %s", name, result.FreezerState, freezeTimeOut)
+	case freeze:
+		result.Note = fmt.Sprintf("the processes of %s are suspended until it's thawed with thaw_unit", name)
+	}
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
+
+// suspend all processes of the unit with the cgroup freezer
+func (conn *Connection) FreezeUnit(ctx context.Context, req *mcp.CallToolRequest, params *FreezeParams) (*mcp.CallToolResult, any, error) {
+	return conn.freezeUnit(ctx, params.Name, true)
+}
+
+// resume the processes of a frozen unit
+func (conn *Connection) ThawUnit(ctx context.Context, req *mcp.CallToolRequest, params *FreezeParams) (*mcp.CallToolResult, any, error) {
+	return conn.freezeUnit(ctx, params.Name, false)
+}
@@ -0,0 +1,69 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreezeUnit(t *testing.T) {
+	oldInterval := freezePollInterval
+	freezePollInterval = time.Millisecond
+	defer func() { freezePollInterval = oldInterval }()
+	activeState := "active"
+	states := []string{"running"}
+	calls := []string{}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				state := states[0]
+				if len(states) > 1 {
+					states = states[1:]
+				}
+				return map[string]interface{}{"ActiveState": activeState, "FreezerState": state}, nil
+			},
+			freezeUnit: func(name string) error {
+				calls = append(calls, "freeze "+name)
+				states = []string{"freezing", "freezing", "frozen"}
+				return nil
+			},
+			thawUnit: func(name string) error {
+				calls = append(calls, "thaw "+name)
+				if name == "broken.service" {
+					return fmt.Errorf("not supported")
+				}
+				states = []string{"running"}
+				return nil
+			},
+		},
+	}
+	res, _, err := conn.FreezeUnit(context.Background(), nil, &FreezeParams{Name: "foo.service"})
+	assert.NoError(t, err)
+	var result FreezeResult
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Equal(t, FreezeResult{
+		Unit:         "foo.service",
+		ActiveState:  "active",
+		FreezerState: "frozen",
+		Note:         "the processes of foo.service are suspended until it's thawed with thaw_unit",
+	}, result)
+
+	res, _, err = conn.ThawUnit(context.Background(), nil, &FreezeParams{Name: "foo.service"})
+	assert.NoError(t, err)
+	result = FreezeResult{}
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Equal(t, FreezeResult{Unit: "foo.service", ActiveState: "active", FreezerState: "running"}, result)
+	assert.Equal(t, []string{"freeze foo.service", "thaw foo.service"}, calls)
+
+	_, _, err = conn.ThawUnit(context.Background(), nil, &FreezeParams{Name: "broken.service"})
+	assert.ErrorContains(t, err, "not supported")
+
+	activeState = "inactive"
+	_, _, err = conn.FreezeUnit(context.Background(), nil, &FreezeParams{Name: "foo.service"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// killPollInterval is the interval in which the processes are checked
+// while waiting for the escalation
+var killPollInterval = 100 * time.Millisecond
+
+// killWaitTime is how long the processes are waited for after SIGKILL,
+// which can't be handled but processes in uninterruptible sleep delay it
+var killWaitTime = time.Second
+
+// ValidKillTargets returns the processes of the unit which can be signalled
+func ValidKillTargets() []string {
+	return []string{string(dbus.Main), string(dbus.Control), string(dbus.All)}
+}
+
+type KillUnitParams struct {
+	Name     string `json:"name" jsonschema:"Exact name of the unit, like foo.service."`
+	Signal   string `json:"signal,omitempty" jsonschema:"Signal to send by name like SIGTERM, SIGHUP or SIGUSR1, or by number. Defaults to SIGTERM."`
+	Whom     string `json:"whom,omitempty" jsonschema:"Processes to signal: main for the main process, control for the control process or all. Defaults to all."`
+	Escalate uint   `json:"escalate,omitempty" jsonschema:"Seconds to wait for the processes to exit after the signal, the remaining processes get SIGKILL. Not set means no escalation."`
+}
+
+type KillResult struct {
+	Unit      string `json:"unit"`
+	Signal    string `json:"signal"`
+	Whom      string `json:"whom"`
+	Signalled []int  `json:"signalled"`
+	Killed    []int  `json:"killed,omitempty"`
+	Remaining []int  `json:"remaining,omitempty"`
+}
+
+// killTargets returns the processes of the unit which get the signal
+func (conn *Connection) killTargets(ctx context.Context, name string, whom dbus.Who) ([]int, error) {
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	switch whom {
+	case dbus.Main, dbus.Control:
+		key := "MainPID"
+		if whom == dbus.Control {
+			key = "ControlPID"
+		}
+		pid, _ := props[key].(uint32)
+		if pid == 0 {
+			return nil, fmt.Errorf("%s has no %s process", name, whom)
+		}
+		return []int{int(pid)}, nil
+	}
+	cgroup, _ := props["ControlGroup"].(string)
+	if cgroup == "" {
+		return nil, fmt.Errorf("%s has no control group, it's not running", name)
+	}
+	pids, err := cgroupPids(filepath.Join(cgroupRoot, filepath.Clean("/"+cgroup)))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read control group of %s: %w", name, err)
+	}
+	return slices.Sorted(maps.Keys(pids)), nil
+}
+
+// alivePids returns the processes which didn't exit, zombies are dead
+func alivePids(pids []int) []int {
+	alive := []int{}
+	for _, pid := range pids {
+		if proc, err := readProcess(pid, time.Time{}); err == nil && !proc.Zombie {
+			alive = append(alive, pid)
+		}
+	}
+	return alive
+}
+
+// waitPids waits until the processes exited or the deadline passed and
+// returns the remaining ones
+func waitPids(ctx context.Context, pids []int, deadline time.Time) ([]int, error) {
+	remaining := alivePids(pids)
+	for len(remaining) > 0 && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(killPollInterval):
+		}
+		remaining = alivePids(remaining)
+	}
+	return remaining, nil
+}
+
+// send a signal to the processes of a unit
+func (conn *Connection) KillUnit(ctx context.Context, req *mcp.CallToolRequest, params *KillUnitParams) (*mcp.CallToolResult, any, error) {
+	if params.Name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	signalName := params.Signal
+	if signalName == "" {
+		signalName = "SIGTERM"
+	}
+	signal, err := ParseSignal(signalName)
+	if err != nil {
+		return nil, nil, err
+	}
+	whom := dbus.Who(params.Whom)
+	if whom == "" {
+		whom = dbus.All
+	}
+	if !slices.Contains(ValidKillTargets(), string(whom)) {
+		return nil, nil, fmt.Errorf("invalid target %s, valid targets are: %v", whom, ValidKillTargets())
+	}
+	if params.Escalate > MaxTimeOut {
+		return nil, nil, fmt.Errorf("not waiting longer than MaxTimeOut(%d) for the escalation", MaxTimeOut)
+	}
+	pids, err := conn.killTargets(ctx, params.Name, whom)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := conn.dbus.KillUnitWithTarget(ctx, params.Name, whom, signal); err != nil {
+		return nil, nil, fmt.Errorf("couldn't send %s to %s: %w", SignalName(signal), params.Name, err)
+	}
+	result := KillResult{
+		Unit:      params.Name,
+		Signal:    SignalName(signal),
+		Whom:      string(whom),
+		Signalled: pids,
+	}
+	if params.Escalate > 0 && signal != int32(syscall.SIGKILL) {
+		remaining, err := waitPids(ctx, pids, time.Now().Add(time.Duration(params.Escalate)*time.Second))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(remaining) > 0 {
+			if err := conn.dbus.KillUnitWithTarget(ctx, params.Name, whom, int32(syscall.SIGKILL)); err != nil {
+				return nil, nil, fmt.Errorf("couldn't send SIGKILL to %s: %w", params.Name, err)
+			}
+			result.Killed = remaining
+			alive, err := waitPids(ctx, remaining, time.Now().Add(killWaitTime))
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(alive) > 0 {
+				result.Remaining = alive
+			}
+		}
+	}
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
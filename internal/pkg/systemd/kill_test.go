@@ -0,0 +1,119 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSignal(t *testing.T) {
+	for name, signal := range map[string]int32{
+		"SIGTERM":     15,
+		"term":        15,
+		"HUP":         1,
+		"SIGUSR1":     10,
+		"9":           9,
+		"SIGRTMIN":    34,
+		"SIGRTMIN+2":  36,
+		"RTMAX-1":     63,
+		" sigkill  ":  9,
+		"SIGWINCH":    28,
+		"SIGRTMAX":    64,
+		"SIGRTMIN+30": 64,
+	} {
+		got, err := ParseSignal(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, signal, got, name)
+	}
+	for _, name := range []string{"", "0", "65", "SIGFOO", "SIGRTMIN+31", "SIGRTMAX+1", "RTMIN+x"} {
+		_, err := ParseSignal(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestKillUnit(t *testing.T) {
+	oldProc, oldCgroup, oldInterval := procRoot, cgroupRoot, killPollInterval
+	procRoot, cgroupRoot, killPollInterval = t.TempDir(), t.TempDir(), time.Millisecond
+	defer func() { procRoot, cgroupRoot, killPollInterval = oldProc, oldCgroup, oldInterval }()
+	writeCgroup(t, cgroupRoot, "system.slice/foo.service", map[string]string{"cgroup.procs": "100\n101\n"})
+	writeProcess(t, procRoot, 100, 1, "foo", "S", "foo\x00")
+	writeProcess(t, procRoot, 101, 100, "foo", "S", "foo\x00")
+	type call struct {
+		target dbus.Who
+		signal int32
+	}
+	calls := []call{}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"ControlGroup": "/system.slice/foo.service",
+					"MainPID":      uint32(100),
+					"ControlPID":   uint32(0),
+				}, nil
+			},
+			killUnitWithTarget: func(name string, target dbus.Who, signal int32) error {
+				calls = append(calls, call{target: target, signal: signal})
+				// the main process handles SIGTERM, the child ignores it
+				if signal == 15 || signal == 9 {
+					os.RemoveAll(filepath.Join(procRoot, "100"))
+				}
+				if signal == 9 {
+					os.RemoveAll(filepath.Join(procRoot, "101"))
+				}
+				return nil
+			},
+		},
+	}
+	kill := func(params *KillUnitParams) KillResult {
+		res, _, err := conn.KillUnit(context.Background(), nil, params)
+		assert.NoError(t, err)
+		var result KillResult
+		assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+		return result
+	}
+
+	result := kill(&KillUnitParams{Name: "foo.service", Signal: "HUP", Whom: "main"})
+	assert.Equal(t, KillResult{Unit: "foo.service", Signal: "SIGHUP", Whom: "main", Signalled: []int{100}}, result)
+	assert.Equal(t, []call{{target: dbus.Main, signal: 1}}, calls)
+
+	calls = []call{}
+	result = kill(&KillUnitParams{Name: "foo.service", Escalate: 1})
+	assert.Equal(t, KillResult{Unit: "foo.service", Signal: "SIGTERM", Whom: "all", Signalled: []int{100, 101}, Killed: []int{101}}, result)
+	assert.Equal(t, []call{{target: dbus.All, signal: 15}, {target: dbus.All, signal: 9}}, calls)
+
+	// a process in uninterruptible sleep survives SIGKILL, the wait for it
+	// is bounded and stops when the request is cancelled
+	writeProcess(t, procRoot, 100, 1, "foo", "S", "foo\x00")
+	writeProcess(t, procRoot, 101, 100, "foo", "D", "foo\x00")
+	conn.dbus.(*mockDbusConnection).killUnitWithTarget = func(name string, target dbus.Who, signal int32) error {
+		calls = append(calls, call{target: target, signal: signal})
+		return nil
+	}
+	oldWait := killWaitTime
+	killWaitTime = 10 * time.Millisecond
+	defer func() { killWaitTime = oldWait }()
+	result = kill(&KillUnitParams{Name: "foo.service", Escalate: 1})
+	assert.Equal(t, []int{100, 101}, result.Remaining)
+	calls = []call{}
+	killWaitTime = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	_, _, err := conn.KillUnit(ctx, nil, &KillUnitParams{Name: "foo.service", Escalate: 1})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []call{{target: dbus.All, signal: 15}, {target: dbus.All, signal: 9}}, calls)
+
+	_, _, err = conn.KillUnit(context.Background(), nil, &KillUnitParams{Name: "foo.service", Whom: "control"})
+	assert.Error(t, err)
+	_, _, err = conn.KillUnit(context.Background(), nil, &KillUnitParams{Name: "foo.service", Whom: "everyone"})
+	assert.Error(t, err)
+	_, _, err = conn.KillUnit(context.Background(), nil, &KillUnitParams{Name: "foo.service", Signal: "SIGFOO"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type DaemonReloadParams struct {
+	Reexec bool `json:"reexec,omitempty" jsonschema:"Execute the manager again instead of only reloading the unit files, like 'systemctl daemon-reexec'. Needed after an update of systemd."`
+}
+
+// reload the unit files or execute the manager again
+func (conn *Connection) DaemonReload(ctx context.Context, req *mcp.CallToolRequest, params *DaemonReloadParams) (*mcp.CallToolResult, any, error) {
+	action := "Reloaded"
+	var err error
+	if params.Reexec {
+		action = "Reexecuted"
+		err = conn.dbus.ReexecuteContext(ctx)
+	} else {
+		err = conn.dbus.ReloadContext(ctx)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	manager := "system manager"
+	if conn.user {
+		manager = "user manager"
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("%s %s", action, manager),
+			},
+		},
+	}, nil, nil
+}
+
+type SystemStateParams struct{}
+
+type SystemState struct {
+	State          string   `json:"state"`
+	Version        string   `json:"version,omitempty"`
+	Virtualization string   `json:"virtualization,omitempty"`
+	DefaultTarget  string   `json:"default_target,omitempty"`
+	NJobs          uint32   `json:"n_jobs"`
+	NFailedUnits   uint32   `json:"n_failed_units"`
+	FailedUnits    []string `json:"failed_units"`
+}
+
+// show the state of the manager like 'systemctl is-system-running'
+func (conn *Connection) SystemState(ctx context.Context, req *mcp.CallToolRequest, params *SystemStateParams) (*mcp.CallToolResult, any, error) {
+	props, err := conn.dbus.GetManagerPropertiesContext(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get manager properties: %w", err)
+	}
+	state := SystemState{FailedUnits: []string{}}
+	state.State, _ = props["SystemState"].(string)
+	state.Version, _ = props["Version"].(string)
+	state.Virtualization, _ = props["Virtualization"].(string)
+	state.NJobs, _ = props["NJobs"].(uint32)
+	state.NFailedUnits, _ = props["NFailedUnits"].(uint32)
+	if target, err := conn.dbus.GetDefaultTargetContext(ctx); err == nil {
+		state.DefaultTarget = target
+	}
+	units, err := conn.dbus.ListUnitsFilteredContext(ctx, []string{"failed"})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, unit := range units {
+		state.FailedUnits = append(state.FailedUnits, unit.Name)
+	}
+	slices.Sort(state.FailedUnits)
+	jsonByte, err := json.Marshal(state)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
+
+// checkTarget checks that the name is a loaded target
+func (conn *Connection) checkTarget(ctx context.Context, name string) (map[string]interface{}, error) {
+	if UnitType(name) != "target" {
+		return nil, fmt.Errorf("%s is not a target", name)
+	}
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if loadState, _ := props["LoadState"].(string); loadState != "loaded" {
+		return nil, fmt.Errorf("target %s is %s", name, loadState)
+	}
+	return props, nil
+}
+
+type DefaultTargetParams struct {
+	Name    string `json:"name,omitempty" jsonschema:"Target to set as default target, like multi-user.target or graphical.target. Without name the default target is returned."`
+	Confirm bool   `json:"confirm,omitempty" jsonschema:"Change the default target. This changes the target the system boots into, so the change has to be confirmed explicitly."`
+}
+
+type DefaultTargetResult struct {
+	DefaultTarget string                      `json:"default_target"`
+	NewTarget     string                      `json:"new_target,omitempty"`
+	Changes       []dbus.EnableUnitFileChange `json:"changes,omitempty"`
+	Note          string                      `json:"note,omitempty"`
+}
+
+// get or set the default target like 'systemctl get-default' and
+// 'systemctl set-default'
+func (conn *Connection) DefaultTarget(ctx context.Context, req *mcp.CallToolRequest, params *DefaultTargetParams) (*mcp.CallToolResult, any, error) {
+	current, err := conn.dbus.GetDefaultTargetContext(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't get default target: %w", err)
+	}
+	result := DefaultTargetResult{DefaultTarget: current}
+	switch {
+	case params.Name == "":
+	case params.Name == current:
+		result.Note = fmt.Sprintf("%s is already the default target", current)
+	default:
+		if _, err := conn.checkTarget(ctx, params.Name); err != nil {
+			return nil, nil, err
+		}
+		result.NewTarget = params.Name
+		if !params.Confirm {
+			result.Note = fmt.Sprintf("the default target wasn't changed, the system would boot into %s instead of %s. Call again with confirm to change it.", params.Name, current)
+			break
+		}
+		result.Changes, err = conn.dbus.SetDefaultTargetContext(ctx, params.Name, true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't set default target: %w", err)
+		}
+		result.DefaultTarget = params.Name
+	}
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
+
+type IsolateParams struct {
+	Name    string `json:"name" jsonschema:"Target to switch to, like rescue.target or multi-user.target."`
+	Confirm bool   `json:"confirm,omitempty" jsonschema:"Switch to the target. All units which aren't needed by the target are stopped, so the switch has to be confirmed explicitly."`
+	TimeOut uint   `json:"timeout,omitempty" jsonschema:"Time to wait for the switch to finish."`
+}
+
+// switch to a target and stop all units not needed by it, like
+// 'systemctl isolate'
+func (conn *Connection) IsolateTarget(ctx context.Context, req *mcp.CallToolRequest, params *IsolateParams) (*mcp.CallToolResult, any, error) {
+	if params.TimeOut > MaxTimeOut {
+		return nil, nil, fmt.Errorf("not waiting longer than MaxTimeOut(%d), longer operation will run in the background and result can be gathered with separate function.", MaxTimeOut)
+	}
+	props, err := conn.checkTarget(ctx, params.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if allow, _ := props["AllowIsolate"].(bool); !allow {
+		return nil, nil, fmt.Errorf("%s doesn't allow to be isolated (AllowIsolate=no)", params.Name)
+	}
+	if !params.Confirm {
+		active, err := conn.dbus.ListUnitsByPatternsContext(ctx, []string{"active"}, []string{"*.target"})
+		if err != nil {
+			return nil, nil, err
+		}
+		targets := []string{}
+		for _, unit := range active {
+			targets = append(targets, unit.Name)
+		}
+		slices.Sort(targets)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("%s wasn't isolated. Isolating it stops all units which aren't needed by it, the active targets are: %s. Call again with confirm to switch to it.", params.Name, strings.Join(targets, ", ")),
+				},
+			},
+		}, nil, nil
+	}
+	jobChan := make(chan string, 1)
+	if _, err := conn.dbus.StartUnitContext(ctx, params.Name, "isolate", jobChan); err != nil {
+		return nil, nil, err
+	}
+	var text string
+	switch result := waitJob(jobChan, params.TimeOut); result {
+	case "":
+		text = fmt.Sprintf("Isolating %s still in progress.", params.Name)
+	case "done":
+		text = fmt.Sprintf("Isolated %s", params.Name)
+	default:
+		text = fmt.Sprintf("Isolating %s finished with result: %s", params.Name, result)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: text,
+			},
+		},
+	}, nil, nil
+}
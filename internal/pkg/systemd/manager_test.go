@@ -0,0 +1,137 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDaemonReload(t *testing.T) {
+	calls := []string{}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			reload:    func() error { calls = append(calls, "reload"); return nil },
+			reexecute: func() error { calls = append(calls, "reexec"); return nil },
+		},
+	}
+	res, _, err := conn.DaemonReload(context.Background(), nil, &DaemonReloadParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Reloaded system manager", res.Content[0].(*mcp.TextContent).Text)
+	conn.user = true
+	res, _, err = conn.DaemonReload(context.Background(), nil, &DaemonReloadParams{Reexec: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "Reexecuted user manager", res.Content[0].(*mcp.TextContent).Text)
+	assert.Equal(t, []string{"reload", "reexec"}, calls)
+}
+
+func TestSystemState(t *testing.T) {
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			managerProperties: func() (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"SystemState":  "degraded",
+					"Version":      "257",
+					"NFailedUnits": uint32(2),
+					"NJobs":        uint32(0),
+				}, nil
+			},
+			getDefaultTarget: func() (string, error) { return "graphical.target", nil },
+			listUnitsFiltered: func(states []string) ([]dbus.UnitStatus, error) {
+				assert.Equal(t, []string{"failed"}, states)
+				return []dbus.UnitStatus{{Name: "foo.service"}, {Name: "bar.mount"}}, nil
+			},
+		},
+	}
+	res, _, err := conn.SystemState(context.Background(), nil, &SystemStateParams{})
+	assert.NoError(t, err)
+	var state SystemState
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &state))
+	assert.Equal(t, SystemState{
+		State:         "degraded",
+		Version:       "257",
+		DefaultTarget: "graphical.target",
+		NFailedUnits:  2,
+		FailedUnits:   []string{"bar.mount", "foo.service"},
+	}, state)
+}
+
+// targetProps returns the properties of the loaded targets
+func targetProps(unitName string) (map[string]interface{}, error) {
+	switch unitName {
+	case "multi-user.target", "rescue.target":
+		return map[string]interface{}{"LoadState": "loaded", "AllowIsolate": true}, nil
+	case "network.target":
+		return map[string]interface{}{"LoadState": "loaded", "AllowIsolate": false}, nil
+	}
+	return map[string]interface{}{"LoadState": "not-found"}, nil
+}
+
+func TestDefaultTarget(t *testing.T) {
+	current := "graphical.target"
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: targetProps,
+			getDefaultTarget: func() (string, error) { return current, nil },
+			setDefaultTarget: func(name string, force bool) ([]dbus.EnableUnitFileChange, error) {
+				current = name
+				return []dbus.EnableUnitFileChange{{Type: "symlink", Filename: "/etc/systemd/system/default.target", Destination: "/usr/lib/systemd/system/" + name}}, nil
+			},
+		},
+	}
+	target := func(params *DefaultTargetParams) DefaultTargetResult {
+		res, _, err := conn.DefaultTarget(context.Background(), nil, params)
+		assert.NoError(t, err)
+		var result DefaultTargetResult
+		assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+		return result
+	}
+	assert.Equal(t, DefaultTargetResult{DefaultTarget: "graphical.target"}, target(&DefaultTargetParams{}))
+
+	result := target(&DefaultTargetParams{Name: "multi-user.target"})
+	assert.Equal(t, "graphical.target", current)
+	assert.Equal(t, "multi-user.target", result.NewTarget)
+	assert.Contains(t, result.Note, "Call again with confirm")
+
+	result = target(&DefaultTargetParams{Name: "multi-user.target", Confirm: true})
+	assert.Equal(t, "multi-user.target", current)
+	assert.Equal(t, "multi-user.target", result.DefaultTarget)
+	assert.Len(t, result.Changes, 1)
+
+	_, _, err := conn.DefaultTarget(context.Background(), nil, &DefaultTargetParams{Name: "foo.target", Confirm: true})
+	assert.Error(t, err)
+	_, _, err = conn.DefaultTarget(context.Background(), nil, &DefaultTargetParams{Name: "foo.service", Confirm: true})
+	assert.Error(t, err)
+}
+
+func TestIsolateTarget(t *testing.T) {
+	started := []string{}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: targetProps,
+			listUnitsByPatterns: func(patterns, states []string) ([]dbus.UnitStatus, error) {
+				return []dbus.UnitStatus{{Name: "multi-user.target"}, {Name: "basic.target"}}, nil
+			},
+			startUnit: func(name string, mode string, ch chan<- string) (int, error) {
+				started = append(started, name+" "+mode)
+				ch <- "done"
+				return 1, nil
+			},
+		},
+	}
+	res, _, err := conn.IsolateTarget(context.Background(), nil, &IsolateParams{Name: "rescue.target"})
+	assert.NoError(t, err)
+	assert.Equal(t, "rescue.target wasn't isolated. Isolating it stops all units which aren't needed by it, the active targets are: basic.target, multi-user.target. Call again with confirm to switch to it.", res.Content[0].(*mcp.TextContent).Text)
+	assert.Empty(t, started)
+
+	res, _, err = conn.IsolateTarget(context.Background(), nil, &IsolateParams{Name: "rescue.target", Confirm: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "Isolated rescue.target", res.Content[0].(*mcp.TextContent).Text)
+	assert.Equal(t, []string{"rescue.target isolate"}, started)
+
+	_, _, err = conn.IsolateTarget(context.Background(), nil, &IsolateParams{Name: "network.target", Confirm: true})
+	assert.ErrorContains(t, err, "AllowIsolate=no")
+}
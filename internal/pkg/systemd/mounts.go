@@ -0,0 +1,297 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// files of the kernel with the current mounts and swaps
+var (
+	mountInfoPath = "/proc/self/mountinfo"
+	swapsPath     = "/proc/swaps"
+)
+
+// KernelMount is a mount or swap as seen by the kernel
+type KernelMount struct {
+	Source  string `json:"source"`
+	FSType  string `json:"fs_type,omitempty"`
+	Options string `json:"options,omitempty"`
+}
+
+// unescapeMountPath decodes the octal escapes like \040 of mountinfo
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, "\\") {
+		return path
+	}
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+4 <= len(path) {
+			if val, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(val))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(path[i])
+	}
+	return sb.String()
+}
+
+// sameDevice compares the device of the unit with the source of the kernel
+// mount, symlinks like /dev/disk/by-uuid are resolved. Sources which aren't
+// paths like tmpfs are compared by their names.
+func sameDevice(what, source string) bool {
+	if what == "" || what == source {
+		return true
+	}
+	if !filepath.IsAbs(what) || !filepath.IsAbs(source) {
+		return false
+	}
+	resolvedWhat, err := filepath.EvalSymlinks(what)
+	if err != nil {
+		return true
+	}
+	resolvedSource, err := filepath.EvalSymlinks(source)
+	if err != nil {
+		return true
+	}
+	return resolvedWhat == resolvedSource
+}
+
+// readMountInfo returns the mounts of the kernel by their mount point, for
+// over mounted paths the last mount wins
+func readMountInfo() (map[string]KernelMount, error) {
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	mounts := make(map[string]KernelMount)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// id parent major:minor root mountpoint options [optional...] - fstype source superoptions
+		pre, post, found := strings.Cut(scanner.Text(), " - ")
+		if !found {
+			continue
+		}
+		preFields := strings.Fields(pre)
+		postFields := strings.Fields(post)
+		if len(preFields) < 6 || len(postFields) < 2 {
+			continue
+		}
+		mount := KernelMount{
+			FSType:  postFields[0],
+			Source:  unescapeMountPath(postFields[1]),
+			Options: preFields[5],
+		}
+		mounts[unescapeMountPath(preFields[4])] = mount
+	}
+	return mounts, scanner.Err()
+}
+
+// findSwap returns the swap of the kernel for the device of the unit, which
+// may be a symlink like /dev/disk/by-uuid
+func findSwap(swaps map[string]KernelMount, what string) (KernelMount, bool) {
+	if swap, ok := swaps[what]; ok {
+		return swap, true
+	}
+	resolved, err := filepath.EvalSymlinks(what)
+	if err != nil {
+		return KernelMount{}, false
+	}
+	for source, swap := range swaps {
+		if resolvedSource, err := filepath.EvalSymlinks(source); err == nil && resolvedSource == resolved {
+			return swap, true
+		}
+	}
+	return KernelMount{}, false
+}
+
+// readSwaps returns the active swaps of the kernel by their device or file
+func readSwaps() (map[string]KernelMount, error) {
+	file, err := os.Open(swapsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	swaps := make(map[string]KernelMount)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Filename Type Size Used Priority
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] == "Filename" {
+			continue
+		}
+		source := unescapeMountPath(fields[0])
+		swaps[source] = KernelMount{Source: source, FSType: fields[1], Options: "pri=" + fields[4]}
+	}
+	return swaps, scanner.Err()
+}
+
+// ValidMountTypes returns the unit types listed by ListMounts
+func ValidMountTypes() []string {
+	return []string{"mount", "automount", "swap"}
+}
+
+type ListMountsParams struct {
+	Types  []string `json:"types,omitempty" jsonschema:"Unit types to list, any of mount, automount and swap. Defaults to all of them."`
+	All    bool     `json:"all,omitempty" jsonschema:"Also list the inactive units."`
+	Failed bool     `json:"failed,omitempty" jsonschema:"Only list the failed units and the units which differ from the state of the kernel."`
+}
+
+type MountInfo struct {
+	Unit        string       `json:"unit"`
+	Type        string       `json:"type"`
+	What        string       `json:"what,omitempty"`
+	Where       string       `json:"where,omitempty"`
+	FSType      string       `json:"fs_type,omitempty"`
+	Options     string       `json:"options,omitempty"`
+	ActiveState string       `json:"active_state"`
+	SubState    string       `json:"sub_state"`
+	Result      string       `json:"result,omitempty"`
+	RequiredBy  []string     `json:"required_by,omitempty"`
+	WantedBy    []string     `json:"wanted_by,omitempty"`
+	SourcePath  string       `json:"source_path,omitempty"`
+	Generated   bool         `json:"generated"`
+	Kernel      *KernelMount `json:"kernel,omitempty"`
+	Problems    []string     `json:"problems,omitempty"`
+}
+
+// mountInfo reads the properties of the unit and compares its state with
+// the mounts or swaps of the kernel
+func (conn *Connection) mountInfo(ctx context.Context, name string, mounts, swaps map[string]KernelMount) (MountInfo, error) {
+	info := MountInfo{Unit: name, Type: UnitType(name)}
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+	if err != nil {
+		return info, err
+	}
+	info.What, _ = props["What"].(string)
+	info.Where, _ = props["Where"].(string)
+	info.FSType, _ = props["Type"].(string)
+	info.Options, _ = props["Options"].(string)
+	info.ActiveState, _ = props["ActiveState"].(string)
+	info.SubState, _ = props["SubState"].(string)
+	info.Result, _ = props["Result"].(string)
+	info.RequiredBy = stringSlice(props, "RequiredBy")
+	info.WantedBy = stringSlice(props, "WantedBy")
+	info.SourcePath, _ = props["SourcePath"].(string)
+	fragment, _ := props["FragmentPath"].(string)
+	info.Generated = strings.HasPrefix(fragment, "/run/systemd/generator")
+	if info.Result == "success" {
+		info.Result = ""
+	}
+	if info.ActiveState == "failed" {
+		problem := fmt.Sprintf("%s failed with result %s", name, info.Result)
+		if info.Generated && info.SourcePath != "" {
+			entry := info.Where
+			if info.Type == "swap" {
+				entry = info.What
+			}
+			problem = fmt.Sprintf("%s was created by a generator from %s and failed with result %s, check the entry for %s", name, info.SourcePath, info.Result, entry)
+		}
+		if len(info.RequiredBy) > 0 {
+			problem += fmt.Sprintf(", required by %s", strings.Join(info.RequiredBy, ", "))
+		}
+		info.Problems = append(info.Problems, problem)
+	}
+	var kernel KernelMount
+	var inKernel bool
+	switch info.Type {
+	case "mount":
+		kernel, inKernel = mounts[info.Where]
+	case "swap":
+		kernel, inKernel = findSwap(swaps, info.What)
+	case "automount":
+		// the autofs mount is replaced by the real mount when accessed
+		kernel, inKernel = mounts[info.Where]
+	}
+	if mounts == nil && info.Type != "swap" || swaps == nil && info.Type == "swap" {
+		return info, nil
+	}
+	if inKernel {
+		info.Kernel = &kernel
+	}
+	active := info.ActiveState == "active" || info.ActiveState == "reloading"
+	switch {
+	case active && !inKernel:
+		info.Problems = append(info.Problems, fmt.Sprintf("%s is active but not found in the kernel", name))
+	case !active && inKernel && info.ActiveState != "activating" && info.ActiveState != "deactivating":
+		info.Problems = append(info.Problems, fmt.Sprintf("%s is %s but mounted in the kernel", name, info.ActiveState))
+	case inKernel && info.Type == "mount" && !sameDevice(info.What, kernel.Source):
+		info.Problems = append(info.Problems, fmt.Sprintf("%s is mounted from %s in the kernel instead of %s", info.Where, kernel.Source, info.What))
+	}
+	return info, nil
+}
+
+// list the mount, automount and swap units
+func (conn *Connection) ListMounts(ctx context.Context, req *mcp.CallToolRequest, params *ListMountsParams) (*mcp.CallToolResult, any, error) {
+	types := params.Types
+	if len(types) == 0 {
+		types = ValidMountTypes()
+	}
+	patterns := []string{}
+	for _, unitType := range types {
+		if !slices.Contains(ValidMountTypes(), unitType) {
+			return nil, nil, fmt.Errorf("invalid type %s, valid types are: %v", unitType, ValidMountTypes())
+		}
+		patterns = append(patterns, "*."+unitType)
+	}
+	states := []string{"active", "failed"}
+	if params.All || params.Failed {
+		states = []string{}
+	}
+	units, err := conn.dbus.ListUnitsByPatternsContext(ctx, states, patterns)
+	if err != nil {
+		return nil, nil, err
+	}
+	notes := []string{}
+	mounts, err := readMountInfo()
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("couldn't read %s: %s", mountInfoPath, err))
+	}
+	swaps, err := readSwaps()
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("couldn't read %s: %s", swapsPath, err))
+	}
+	slices.SortFunc(units, func(a, b dbus.UnitStatus) int { return strings.Compare(a.Name, b.Name) })
+	txtContentList := []mcp.Content{}
+	for _, unit := range units {
+		info, err := conn.mountInfo(ctx, unit.Name, mounts, swaps)
+		if err != nil {
+			return nil, nil, err
+		}
+		if params.Failed && info.ActiveState != "failed" && len(info.Problems) == 0 {
+			continue
+		}
+		jsonByte, err := json.Marshal(info)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	if len(txtContentList) == 0 {
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: "no matching units found",
+		})
+	}
+	for _, note := range notes {
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: note,
+		})
+	}
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+	}, nil, nil
+}
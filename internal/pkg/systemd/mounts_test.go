@@ -0,0 +1,90 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListMounts(t *testing.T) {
+	dir := t.TempDir()
+	oldMountInfo, oldSwaps := mountInfoPath, swapsPath
+	mountInfoPath, swapsPath = filepath.Join(dir, "mountinfo"), filepath.Join(dir, "swaps")
+	defer func() { mountInfoPath, swapsPath = oldMountInfo, oldSwaps }()
+	assert.NoError(t, os.WriteFile(mountInfoPath, []byte(
+		"22 1 0:21 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n"+
+			"40 22 0:35 / /mnt/my\\040data rw,nosuid shared:20 - tmpfs tmpfs rw\n"+
+			"41 22 0:36 / /srv rw shared:21 - xfs /dev/sdc1 rw\n"), 0o644))
+	assert.NoError(t, os.WriteFile(swapsPath, []byte(
+		"Filename\tType\tSize\tUsed\tPriority\n/dev/sda2 partition 1048572 0 -2\n"), 0o644))
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			listUnitsByPatterns: func(patterns []string, states []string) ([]dbus.UnitStatus, error) {
+				return []dbus.UnitStatus{{Name: "srv.mount"}, {Name: "data.mount"}, {Name: "mnt-my\\x20data.mount"}, {Name: "dev-sda2.swap"}}, nil
+			},
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				switch unitName {
+				case "data.mount":
+					return map[string]interface{}{
+						"What":         "/dev/sdb1",
+						"Where":        "/data",
+						"Type":         "ext4",
+						"ActiveState":  "failed",
+						"SubState":     "failed",
+						"Result":       "exit-code",
+						"RequiredBy":   []string{"local-fs.target"},
+						"SourcePath":   "/etc/fstab",
+						"FragmentPath": "/run/systemd/generator/data.mount",
+					}, nil
+				case "mnt-my\\x20data.mount":
+					return map[string]interface{}{
+						"What":        "tmpfs",
+						"Where":       "/mnt/my data",
+						"Type":        "tmpfs",
+						"ActiveState": "active",
+						"SubState":    "mounted",
+					}, nil
+				case "srv.mount":
+					return map[string]interface{}{
+						"What":        "/dev/sdc1",
+						"Where":       "/srv",
+						"ActiveState": "inactive",
+						"SubState":    "dead",
+					}, nil
+				}
+				return map[string]interface{}{
+					"What":        "/dev/sda2",
+					"ActiveState": "active",
+					"SubState":    "active",
+				}, nil
+			},
+		},
+	}
+	res, _, err := conn.ListMounts(context.Background(), nil, &ListMountsParams{})
+	assert.NoError(t, err)
+	infos := map[string]MountInfo{}
+	for _, content := range res.Content {
+		var info MountInfo
+		assert.NoError(t, json.Unmarshal([]byte(content.(*mcp.TextContent).Text), &info))
+		infos[info.Unit] = info
+	}
+	assert.Len(t, infos, 4)
+	assert.True(t, infos["data.mount"].Generated)
+	assert.Equal(t, []string{"data.mount was created by a generator from /etc/fstab and failed with result exit-code, check the entry for /data, required by local-fs.target"}, infos["data.mount"].Problems)
+	assert.Equal(t, &KernelMount{Source: "tmpfs", FSType: "tmpfs", Options: "rw,nosuid"}, infos["mnt-my\\x20data.mount"].Kernel)
+	assert.Empty(t, infos["mnt-my\\x20data.mount"].Problems)
+	assert.Equal(t, []string{"srv.mount is inactive but mounted in the kernel"}, infos["srv.mount"].Problems)
+	assert.Equal(t, "pri=-2", infos["dev-sda2.swap"].Kernel.Options)
+
+	res, _, err = conn.ListMounts(context.Background(), nil, &ListMountsParams{Failed: true})
+	assert.NoError(t, err)
+	assert.Len(t, res.Content, 2)
+	_, _, err = conn.ListMounts(context.Background(), nil, &ListMountsParams{Types: []string{"device"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,404 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// directories for the drop-ins of the system manager, the runtime ones are
+// gone after a reboot
+var (
+	systemConfigDir  = "/etc/systemd/system"
+	systemRuntimeDir = "/run/systemd/system"
+)
+
+// configDir returns the directory in which the drop-ins of the manager are
+// written
+func (conn *Connection) configDir(runtime bool) (string, error) {
+	if !conn.user {
+		if runtime {
+			return systemRuntimeDir, nil
+		}
+		return systemConfigDir, nil
+	}
+	if runtime {
+		dir := os.Getenv("XDG_RUNTIME_DIR")
+		if dir == "" {
+			return "", fmt.Errorf("XDG_RUNTIME_DIR not set, can't write runtime drop-ins")
+		}
+		return filepath.Join(dir, "systemd/user"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "systemd/user"), nil
+}
+
+// maxUnitNameLength is the maximal length of a unit name, see
+// systemd.unit(5)
+const maxUnitNameLength = 255
+
+// validUnitNameChar returns true for the characters which systemd allows
+// in unit names, other characters are escaped like '\x2d'
+func validUnitNameChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.ContainsRune(":-_.\\@", c)
+}
+
+// checkUnitName checks that the name is a valid unit name, so it can be
+// used as part of a path and doesn't contain glob patterns
+func checkUnitName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name of the unit is required")
+	}
+	if len(name) > maxUnitNameLength || strings.HasPrefix(name, ".") || strings.IndexFunc(name, func(c rune) bool { return !validUnitNameChar(c) }) >= 0 {
+		return fmt.Errorf("invalid unit name: %s", name)
+	}
+	if KnownSections(UnitType(name)) == nil {
+		return fmt.Errorf("unknown unit type of %s", name)
+	}
+	return nil
+}
+
+// dropInFile returns the name of the drop-in file, which defaults to
+// override.conf
+func dropInFile(dropIn string) (string, error) {
+	if dropIn == "" {
+		dropIn = "override"
+	}
+	dropIn = strings.TrimSuffix(dropIn, ".conf")
+	if dropIn == "" || strings.ContainsAny(dropIn, "/\\*?[") || strings.HasPrefix(dropIn, ".") {
+		return "", fmt.Errorf("invalid drop-in name: %s", dropIn)
+	}
+	return dropIn + ".conf", nil
+}
+
+// checkDropIn parses the content of a drop-in and checks that all the
+// sections and keys are known for the unit type
+func checkDropIn(name, content string) error {
+	opts, errs, err := ParseUnitFile(strings.NewReader(content), "")
+	if err != nil {
+		return err
+	}
+	unitType := UnitType(name)
+	msgs := []string{}
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	for _, opt := range opts {
+		knownSection, knownKey := CheckDirective(unitType, opt.Section, opt.Name)
+		if !knownSection {
+			msgs = append(msgs, fmt.Sprintf("line %d: unknown section [%s] for %s units, valid sections are: %v", opt.Line, opt.Section, unitType, KnownSections(unitType)))
+		} else if !knownKey {
+			msgs = append(msgs, fmt.Sprintf("line %d: unknown key %s in section [%s]", opt.Line, opt.Name, opt.Section))
+		}
+	}
+	if len(opts) == 0 && len(msgs) == 0 {
+		msgs = append(msgs, "no settings in drop-in, use revert_unit_overrides to remove it")
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("invalid drop-in: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// checkDropInPolicy checks the commands of the drop-in against the policy,
+// as an override can change the commands of any unit. The commands have to
+// be absolute paths without specifiers or variables, so that they can be
+// checked, and the environment, the file system and the search path must
+// not change the executables which are run.
+func (conn *Connection) checkDropInPolicy(content string) error {
+	opts, _, err := ParseUnitFile(strings.NewReader(content), "")
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		var err error
+		switch {
+		case opt.Value == "":
+			// resets the setting of the unit file
+		case slices.Contains(commandDirectives, opt.Name):
+			executable := commandExecutable(opt.Value)
+			if executable == "" {
+				return fmt.Errorf("line %d: the command of %s can't be checked against the policy, use an absolute path without specifiers or variables", opt.Line, opt.Name)
+			}
+			err = conn.policy.CheckExecutable(executable)
+		case slices.Contains(redirectDirectives, opt.Name):
+			err = conn.policy.CheckDirective(opt.Name)
+		case opt.Name == "Environment":
+			var env []string
+			if env, err = SplitWords(opt.Value); err == nil {
+				err = policy.CheckEnvironment(env)
+			}
+		case opt.Name == "EnvironmentFile":
+			err = checkEnvironmentFile(opt.Value)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", opt.Line, err)
+		}
+	}
+	return nil
+}
+
+// checkEnvironmentFile checks the variables of the files of an
+// EnvironmentFile= setting against the policy. Every line with an
+// assignment is checked, also the ones which systemd would take as
+// continuation of a quoted value.
+func checkEnvironmentFile(value string) error {
+	pattern, optional := strings.CutPrefix(value, "-")
+	if !filepath.IsAbs(pattern) || strings.Contains(pattern, "%") {
+		return fmt.Errorf("environment file %s can't be checked against the policy, use an absolute path without specifiers", value)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid environment file %s: %w", value, err)
+	}
+	if len(files) == 0 && !optional {
+		return fmt.Errorf("environment file %s doesn't exist", pattern)
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("couldn't check environment file %s: %w", file, err)
+		}
+		env := []string{}
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+			if name, _, found := strings.Cut(line, "="); found {
+				env = append(env, strings.TrimSpace(name)+"=")
+			}
+		}
+		if err := policy.CheckEnvironment(env); err != nil {
+			return fmt.Errorf("environment file %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// unifiedDiff returns the diff between the old and new content of the file,
+// an empty content is shown as /dev/null
+func unifiedDiff(path, oldContent, newContent string) (string, error) {
+	from, to := path, path
+	if oldContent == "" {
+		from = "/dev/null"
+	}
+	if newContent == "" {
+		to = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(oldContent),
+		B:        difflib.SplitLines(newContent),
+		FromFile: from,
+		ToFile:   to,
+		Context:  3,
+	})
+}
+
+// maxBackups is the number of backups which are kept of every drop-in
+const maxBackups = 5
+
+// backupFile copies the file next to itself with a timestamp, the suffix
+// makes sure that systemd doesn't read the backup as drop-in. Only the
+// latest maxBackups backups of the file are kept.
+func backupFile(path string, content []byte) (string, error) {
+	backup := fmt.Sprintf("%s.%s.bak", path, time.Now().Format("20060102-150405.000000000"))
+	if err := os.WriteFile(backup, content, 0o644); err != nil {
+		return "", fmt.Errorf("couldn't write backup: %w", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return backup, nil
+	}
+	// the timestamps sort in the order of the backups
+	backups := []string{}
+	prefix := filepath.Base(path) + "."
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), prefix) && strings.HasSuffix(entry.Name(), ".bak") {
+			backups = append(backups, entry.Name())
+		}
+	}
+	for len(backups) > maxBackups {
+		os.Remove(filepath.Join(filepath.Dir(path), backups[0]))
+		backups = backups[1:]
+	}
+	return backup, nil
+}
+
+type OverrideResult struct {
+	Unit   string `json:"unit"`
+	File   string `json:"file"`
+	Backup string `json:"backup,omitempty"`
+	Diff   string `json:"diff"`
+	Error  string `json:"error,omitempty"`
+}
+
+// overrideResults returns the results as json and runs daemon-reload if
+// anything changed
+func (conn *Connection) overrideResults(ctx context.Context, results []OverrideResult) (*mcp.CallToolResult, any, error) {
+	isError := false
+	if len(results) > 0 {
+		if err := conn.dbus.ReloadContext(ctx); err != nil {
+			for i := range results {
+				results[i].Error = fmt.Sprintf("daemon-reload failed: %s", err)
+			}
+			isError = true
+		}
+	}
+	txtContentList := []mcp.Content{}
+	for _, result := range results {
+		jsonByte, err := json.Marshal(result)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+		IsError: isError,
+	}, nil, nil
+}
+
+type EditOverrideParams struct {
+	Name    string `json:"name" jsonschema:"Exact name of the unit, like foo.service."`
+	Content string `json:"content" jsonschema:"Complete content of the drop-in with section headers, like '[Service]\nRestart=always'. An empty assignment like 'ExecStart=' resets the value of the unit file. Replaces the existing content of the drop-in. Commands like ExecStart must be absolute paths allowed by the policy of the server."`
+	DropIn  string `json:"drop_in,omitempty" jsonschema:"Name of the drop-in file in the <unit>.d directory, defaults to override.conf."`
+	Runtime bool   `json:"runtime,omitempty" jsonschema:"Write the drop-in to /run so that it's gone after the next reboot, instead of /etc."`
+}
+
+// write a drop-in for a unit and reload the daemon
+func (conn *Connection) EditOverride(ctx context.Context, req *mcp.CallToolRequest, params *EditOverrideParams) (*mcp.CallToolResult, any, error) {
+	if err := checkUnitName(params.Name); err != nil {
+		return nil, nil, err
+	}
+	file, err := dropInFile(params.DropIn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkDropIn(params.Name, params.Content); err != nil {
+		return nil, nil, err
+	}
+	if err := conn.checkDropInPolicy(params.Content); err != nil {
+		return nil, nil, err
+	}
+	dir, err := conn.configDir(params.Runtime)
+	if err != nil {
+		return nil, nil, err
+	}
+	path := filepath.Join(dir, params.Name+".d", file)
+	content := params.Content
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	result := OverrideResult{Unit: params.Name, File: path}
+	oldContent, readErr := os.ReadFile(path)
+	if readErr != nil && !errors.Is(readErr, fs.ErrNotExist) {
+		return nil, nil, readErr
+	}
+	if string(oldContent) == content {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("nothing changed for %s", path),
+				},
+			},
+		}, nil, nil
+	}
+	if readErr == nil {
+		if result.Backup, err = backupFile(path, oldContent); err != nil {
+			return nil, nil, err
+		}
+	}
+	if result.Diff, err = unifiedDiff(path, string(oldContent), content); err != nil {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	// write to a temporary file first so that a reload never sees a partial
+	// drop-in, the suffix isn't read by systemd
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		return nil, nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, nil, err
+	}
+	return conn.overrideResults(ctx, []OverrideResult{result})
+}
+
+type RevertOverrideParams struct {
+	Name   string `json:"name" jsonschema:"Exact name of the unit, like foo.service."`
+	DropIn string `json:"drop_in,omitempty" jsonschema:"Only remove this drop-in, if not set all drop-ins of the unit in /etc and /run are removed, like 'systemctl revert'."`
+}
+
+// remove the drop-ins of a unit and reload the daemon
+func (conn *Connection) RevertOverride(ctx context.Context, req *mcp.CallToolRequest, params *RevertOverrideParams) (*mcp.CallToolResult, any, error) {
+	if err := checkUnitName(params.Name); err != nil {
+		return nil, nil, err
+	}
+	pattern := "*.conf"
+	if params.DropIn != "" {
+		file, err := dropInFile(params.DropIn)
+		if err != nil {
+			return nil, nil, err
+		}
+		pattern = file
+	}
+	results := []OverrideResult{}
+	for _, runtime := range []bool{false, true} {
+		dir, err := conn.configDir(runtime)
+		if err != nil {
+			continue
+		}
+		paths, err := filepath.Glob(filepath.Join(dir, params.Name+".d", pattern))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range paths {
+			result := OverrideResult{Unit: params.Name, File: path}
+			oldContent, err := os.ReadFile(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			if result.Backup, err = backupFile(path, oldContent); err != nil {
+				return nil, nil, err
+			}
+			if result.Diff, err = unifiedDiff(path, string(oldContent), ""); err != nil {
+				return nil, nil, err
+			}
+			if err := os.Remove(path); err != nil {
+				return nil, nil, err
+			}
+			results = append(results, result)
+		}
+	}
+	if len(results) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("no drop-ins found for %s", params.Name),
+				},
+			},
+		}, nil, nil
+	}
+	return conn.overrideResults(ctx, results)
+}
@@ -0,0 +1,145 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditAndRevertOverride(t *testing.T) {
+	dir := t.TempDir()
+	oldConfig, oldRuntime := systemConfigDir, systemRuntimeDir
+	systemConfigDir, systemRuntimeDir = filepath.Join(dir, "etc"), filepath.Join(dir, "run")
+	defer func() { systemConfigDir, systemRuntimeDir = oldConfig, oldRuntime }()
+	reloads := 0
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			reload: func() error {
+				reloads++
+				return nil
+			},
+		},
+	}
+	path := filepath.Join(systemConfigDir, "foo.service.d", "override.conf")
+
+	_, _, err := conn.EditOverride(context.Background(), nil, &EditOverrideParams{Name: "foo.service", Content: "[Service]\nRestartSecs=5\n"})
+	assert.ErrorContains(t, err, "unknown key RestartSecs")
+	_, _, err = conn.EditOverride(context.Background(), nil, &EditOverrideParams{Name: "foo.service", Content: "[Timer]\nOnCalendar=daily\n"})
+	assert.ErrorContains(t, err, "unknown section [Timer]")
+	_, _, err = conn.EditOverride(context.Background(), nil, &EditOverrideParams{Name: "../foo.service", Content: "[Service]\nRestart=always\n"})
+	assert.Error(t, err)
+	assert.Equal(t, 0, reloads)
+
+	res, _, err := conn.EditOverride(context.Background(), nil, &EditOverrideParams{Name: "foo.service", Content: "[Service]\nRestart=always"})
+	assert.NoError(t, err)
+	var result OverrideResult
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Equal(t, path, result.File)
+	assert.Empty(t, result.Backup)
+	assert.Contains(t, result.Diff, "--- /dev/null")
+	assert.Contains(t, result.Diff, "+Restart=always")
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "[Service]\nRestart=always\n", string(content))
+	assert.Equal(t, 1, reloads)
+
+	res, _, err = conn.EditOverride(context.Background(), nil, &EditOverrideParams{Name: "foo.service", Content: "[Service]\nRestart=on-failure\n"})
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.NotEmpty(t, result.Backup)
+	assert.Contains(t, result.Diff, "-Restart=always")
+	assert.Contains(t, result.Diff, "+Restart=on-failure")
+	backup, err := os.ReadFile(result.Backup)
+	assert.NoError(t, err)
+	assert.Equal(t, "[Service]\nRestart=always\n", string(backup))
+
+	res, _, err = conn.RevertOverride(context.Background(), nil, &RevertOverrideParams{Name: "foo.service"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Content, 1)
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Contains(t, result.Diff, "+++ /dev/null")
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, 3, reloads)
+}
+
+func TestOverrideUnitName(t *testing.T) {
+	dir := t.TempDir()
+	oldConfig, oldRuntime := systemConfigDir, systemRuntimeDir
+	systemConfigDir, systemRuntimeDir = filepath.Join(dir, "etc"), filepath.Join(dir, "run")
+	defer func() { systemConfigDir, systemRuntimeDir = oldConfig, oldRuntime }()
+	for _, unit := range []string{"foo.service", "bar.service"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(systemConfigDir, unit+".d"), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(systemConfigDir, unit+".d", "override.conf"), []byte("[Service]\nRestart=always\n"), 0o644))
+	}
+	conn := &Connection{dbus: &mockDbusConnection{}}
+	for _, name := range []string{"*.service", "foo?.service", "[fb]oo.service", "foo bar.service", ".service"} {
+		_, _, err := conn.RevertOverride(context.Background(), nil, &RevertOverrideParams{Name: name})
+		assert.ErrorContains(t, err, "invalid unit name", name)
+	}
+	_, _, err := conn.RevertOverride(context.Background(), nil, &RevertOverrideParams{Name: "foo.service", DropIn: "*"})
+	assert.ErrorContains(t, err, "invalid drop-in name")
+	for _, unit := range []string{"foo.service", "bar.service"} {
+		_, err := os.Stat(filepath.Join(systemConfigDir, unit+".d", "override.conf"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, checkUnitName(`dev-disk-by\x2duuid-1234.device`))
+	assert.NoError(t, checkUnitName("getty@tty1.service"))
+}
+
+func TestOverridePolicy(t *testing.T) {
+	dir := t.TempDir()
+	oldConfig := systemConfigDir
+	systemConfigDir = filepath.Join(dir, "etc")
+	defer func() { systemConfigDir = oldConfig }()
+	conn := &Connection{dbus: &mockDbusConnection{reload: func() error { return nil }}}
+	edit := func(content string) error {
+		_, _, err := conn.EditOverride(context.Background(), nil, &EditOverrideParams{Name: "foo.service", Content: content})
+		return err
+	}
+	assert.ErrorContains(t, edit("[Service]\nExecStart=\nExecStart=/usr/bin/sleep 10\n"), "-allow-exec")
+	conn.policy = &policy.Policy{AllowedExecutables: []string{"/usr/bin/*"}}
+	assert.ErrorContains(t, edit("[Service]\nExecStartPre=-/tmp/evil\n"), "not allowed by the policy")
+	assert.ErrorContains(t, edit("[Service]\nExecStart=sleep 10\n"), "absolute path")
+	assert.ErrorContains(t, edit("[Service]\nExecStart=${CMD}\n"), "can't be checked")
+	assert.ErrorContains(t, edit("[Service]\nEnvironment=\"FOO=1\" \"LD_PRELOAD=/tmp/x.so\"\n"), "LD_PRELOAD")
+	assert.ErrorContains(t, edit("[Service]\nEnvironment=FOO=1 LD_PRELOAD=\"/tmp/x.so\"\n"), "LD_PRELOAD")
+	assert.ErrorContains(t, edit("[Service]\nEnvironment='FOO=1 PATH=/tmp\n"), "unterminated quote")
+	assert.ErrorContains(t, edit("[Service]\nEnvironment=\\x50ATH=/tmp\n"), "PATH")
+	assert.ErrorContains(t, edit("[Service]\nExecStart=\"/usr/bin/x /../../tmp/evil\"\n"), "/tmp/evil is not allowed")
+	for _, directive := range []string{"PassEnvironment=LD_PRELOAD", "BindPaths=/tmp/evil:/usr/bin/sleep", "RootDirectory=/tmp/root", "ExecSearchPath=/tmp"} {
+		assert.ErrorContains(t, edit("[Service]\n"+directive+"\n"), "-allow-directive", directive)
+	}
+	envFile := filepath.Join(dir, "env")
+	assert.NoError(t, os.WriteFile(envFile, []byte("# comment\nFOO=1\n export LD_LIBRARY_PATH=/tmp\nLD_PRELOAD = /tmp/x.so\n"), 0o644))
+	assert.ErrorContains(t, edit("[Service]\nEnvironmentFile="+envFile+"\n"), "LD_PRELOAD")
+	assert.ErrorContains(t, edit("[Service]\nEnvironmentFile="+filepath.Join(dir, "missing")+"\n"), "doesn't exist")
+	assert.ErrorContains(t, edit("[Service]\nEnvironmentFile=%h/env\n"), "can't be checked")
+	assert.NoError(t, os.WriteFile(envFile, []byte("FOO=1\nBAR='a b'\n"), 0o644))
+	assert.NoError(t, edit("[Service]\nEnvironmentFile="+envFile+"\nEnvironmentFile=-"+filepath.Join(dir, "missing")+"\n"))
+	assert.NoError(t, edit("[Service]\nExecStart=\nExecStart=/usr/bin/sleep 10\nEnvironment=FOO=1 \"BAR=a b\"\n"))
+	conn.policy.AllowedDirectives = []string{"BindReadOnlyPaths"}
+	assert.NoError(t, edit("[Service]\nBindReadOnlyPaths=/srv/data\n"))
+	assert.ErrorContains(t, edit("[Service]\nBindPaths=/srv/data\n"), "not allowed by the policy")
+}
+
+func TestBackupRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "override.conf")
+	backups := map[string]bool{}
+	for i := 0; i < maxBackups+3; i++ {
+		backup, err := backupFile(path, []byte("[Service]\n"))
+		assert.NoError(t, err)
+		backups[backup] = true
+	}
+	assert.Len(t, backups, maxBackups+3, "every backup has its own name")
+	matches, err := filepath.Glob(path + ".*.bak")
+	assert.NoError(t, err)
+	assert.Len(t, matches, maxBackups)
+}
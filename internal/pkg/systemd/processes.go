@@ -0,0 +1,279 @@
+package systemd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// procRoot is the mount point of procfs
+var procRoot = "/proc"
+
+// userHZ is the unit of the times in /proc/<pid>/stat
+const userHZ = 100
+
+type ProcessInfo struct {
+	PID       int    `json:"pid"`
+	PPID      int    `json:"ppid"`
+	Comm      string `json:"comm"`
+	Cmdline   string `json:"cmdline,omitempty"`
+	User      string `json:"user,omitempty"`
+	RSS       uint64 `json:"rss"`
+	State     string `json:"state"`
+	StartTime string `json:"start_time,omitempty"`
+	Cgroup    string `json:"cgroup,omitempty"`
+	Zombie    bool   `json:"zombie,omitempty"`
+	Leftover  bool   `json:"leftover,omitempty"`
+}
+
+type UnitProcesses struct {
+	Unit         string        `json:"unit"`
+	ControlGroup string        `json:"control_group"`
+	MainPID      uint32        `json:"main_pid,omitempty"`
+	ControlPID   uint32        `json:"control_pid,omitempty"`
+	Processes    []ProcessInfo `json:"processes"`
+	Tree         string        `json:"tree"`
+	Warnings     []string      `json:"warnings,omitempty"`
+}
+
+// cgroupPids returns the processes of the cgroup and its children by the
+// path of the cgroup relative to the one of the unit
+func cgroupPids(dir string) (map[int]string, error) {
+	pids := make(map[int]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "cgroup.procs" {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			// the cgroup may be removed while walking
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, filepath.Dir(path))
+		if rel == "." {
+			rel = ""
+		}
+		for _, line := range strings.Fields(string(content)) {
+			if pid, err := strconv.Atoi(line); err == nil {
+				pids[pid] = rel
+			}
+		}
+		return nil
+	})
+	return pids, err
+}
+
+// bootTime reads the time of the boot from /proc/stat
+func bootTime() (time.Time, error) {
+	f, err := os.Open(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, found := strings.CutPrefix(scanner.Text(), "btime "); found {
+			sec, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no btime in %s", filepath.Join(procRoot, "stat"))
+}
+
+// readProcess reads the process from /proc/<pid>/stat, status and cmdline
+func readProcess(pid int, boot time.Time) (ProcessInfo, error) {
+	info := ProcessInfo{PID: pid}
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return info, err
+	}
+	// the comm may contain spaces and parentheses, so it's cut at the last
+	// parenthesis
+	start := strings.IndexByte(string(stat), '(')
+	end := strings.LastIndexByte(string(stat), ')')
+	if start < 0 || end < start {
+		return info, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	info.Comm = string(stat[start+1 : end])
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 20 {
+		return info, fmt.Errorf("invalid stat of process %d", pid)
+	}
+	info.State = fields[0]
+	info.Zombie = info.State == "Z"
+	info.PPID, _ = strconv.Atoi(fields[1])
+	if ticks, err := strconv.ParseUint(fields[19], 10, 64); err == nil && !boot.IsZero() {
+		info.StartTime = formatTime(boot.Add(time.Duration(ticks) * time.Second / userHZ))
+	}
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		info.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+	if status, err := os.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			key, value, _ := strings.Cut(line, ":")
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				continue
+			}
+			switch key {
+			case "Uid":
+				info.User = fields[0]
+				if u, err := user.LookupId(fields[0]); err == nil {
+					info.User = u.Username
+				}
+			case "VmRSS":
+				kb, _ := strconv.ParseUint(fields[0], 10, 64)
+				info.RSS = kb * 1024
+			}
+		}
+	}
+	return info, nil
+}
+
+// processTree renders the processes like 'systemctl status', children are
+// below their parent and the processes without parent in the cgroup are at
+// the top
+func processTree(procs []ProcessInfo) string {
+	byPID := make(map[int]ProcessInfo, len(procs))
+	children := make(map[int][]int)
+	for _, proc := range procs {
+		byPID[proc.PID] = proc
+	}
+	roots := []int{}
+	for _, proc := range procs {
+		if _, ok := byPID[proc.PPID]; ok && proc.PPID != proc.PID {
+			children[proc.PPID] = append(children[proc.PPID], proc.PID)
+		} else {
+			roots = append(roots, proc.PID)
+		}
+	}
+	var sb strings.Builder
+	var walk func(pids []int, prefix string)
+	walk = func(pids []int, prefix string) {
+		slices.Sort(pids)
+		for i, pid := range pids {
+			proc := byPID[pid]
+			branch, indent := "├─", "│ "
+			if i == len(pids)-1 {
+				branch, indent = "└─", "  "
+			}
+			cmd := proc.Cmdline
+			if cmd == "" {
+				cmd = "[" + proc.Comm + "]"
+			}
+			fmt.Fprintf(&sb, "%s%s%d %s", prefix, branch, pid, cmd)
+			if proc.Zombie {
+				sb.WriteString(" <defunct>")
+			}
+			sb.WriteString("\n")
+			walk(children[pid], prefix+indent)
+		}
+	}
+	walk(roots, "")
+	return sb.String()
+}
+
+type UnitProcessesParams struct {
+	Name string `json:"name" jsonschema:"Exact name of the unit, like foo.service."`
+}
+
+// list the processes of the unit as tree
+func (conn *Connection) UnitProcesses(ctx context.Context, req *mcp.CallToolRequest, params *UnitProcessesParams) (*mcp.CallToolResult, any, error) {
+	if params.Name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, params.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := UnitProcesses{Unit: params.Name, Processes: []ProcessInfo{}}
+	result.ControlGroup, _ = props["ControlGroup"].(string)
+	if result.ControlGroup == "" {
+		return nil, nil, fmt.Errorf("%s has no control group, it's not running", params.Name)
+	}
+	result.MainPID, _ = props["MainPID"].(uint32)
+	result.ControlPID, _ = props["ControlPID"].(uint32)
+	pids, err := cgroupPids(filepath.Join(cgroupRoot, filepath.Clean("/"+result.ControlGroup)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't read control group of %s: %w", params.Name, err)
+	}
+	boot, err := bootTime()
+	if err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("couldn't read boot time: %s", err))
+	}
+	for _, pid := range slices.Sorted(maps.Keys(pids)) {
+		proc, err := readProcess(pid, boot)
+		if err != nil {
+			// the process exited in the meantime
+			continue
+		}
+		proc.Cgroup = pids[pid]
+		result.Processes = append(result.Processes, proc)
+	}
+	// processes which outlived the main process are left over, as are the
+	// ones whose parent exited, so they were reparented out of the cgroup.
+	// While the service is activating or reloading there may be no main
+	// process yet, like for Type=oneshot during ExecStart, and forking
+	// services without PIDFile may have no known main process at all.
+	isService := UnitType(params.Name) == "service"
+	activeState, _ := props["ActiveState"].(string)
+	serviceType, _ := props["Type"].(string)
+	mainExited := result.MainPID == 0 && activeState != "activating" && activeState != "reloading" && serviceType != "forking"
+	for i, proc := range result.Processes {
+		_, parentInCgroup := pids[proc.PPID]
+		ownProcess := uint32(proc.PID) == result.MainPID || uint32(proc.PID) == result.ControlPID
+		if isService && !ownProcess && (mainExited || result.MainPID != 0 && !parentInCgroup) {
+			result.Processes[i].Leftover = true
+		}
+	}
+	zombies, leftovers := 0, 0
+	for _, proc := range result.Processes {
+		if proc.Zombie {
+			zombies++
+		}
+		if proc.Leftover {
+			leftovers++
+		}
+	}
+	if zombies > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d zombie processes, their parent doesn't reap them", zombies))
+	}
+	if leftovers > 0 {
+		if result.MainPID == 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%d processes outlived the main process", leftovers))
+		} else {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%d processes were left over by exited parents", leftovers))
+		}
+	}
+	result.Tree = processTree(result.Processes)
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,128 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeProcess creates the procfs files of a process, which started 10s
+// after the boot
+func writeProcess(t *testing.T, root string, pid, ppid int, comm, state, cmdline string) {
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	stat := fmt.Sprintf("%d (%s) %s %d 1 1 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 1000 100 10\n", pid, comm, state, ppid)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "status"), []byte("Name:\tfoo\nUid:\t0\t0\t0\t0\nVmRSS:\t    1024 kB\n"), 0o644))
+}
+
+func TestUnitProcesses(t *testing.T) {
+	oldProc, oldCgroup := procRoot, cgroupRoot
+	procRoot, cgroupRoot = t.TempDir(), t.TempDir()
+	defer func() { procRoot, cgroupRoot = oldProc, oldCgroup }()
+	assert.NoError(t, os.WriteFile(filepath.Join(procRoot, "stat"), []byte("cpu 1 2 3\nbtime 1700000000\n"), 0o644))
+	writeCgroup(t, cgroupRoot, "system.slice/foo.service", map[string]string{"cgroup.procs": "100\n101\n102\n"})
+	writeCgroup(t, cgroupRoot, "system.slice/foo.service/worker", map[string]string{"cgroup.procs": "103\n"})
+	writeProcess(t, procRoot, 100, 1, "foo", "S", "/usr/bin/foo\x00--daemon\x00")
+	writeProcess(t, procRoot, 101, 100, "foo (worker)", "Z", "")
+	writeProcess(t, procRoot, 102, 1, "sleep", "S", "sleep\x00infinity\x00")
+	writeProcess(t, procRoot, 103, 100, "bar", "R", "bar\x00")
+	mainPID := uint32(100)
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"ControlGroup": "/system.slice/foo.service",
+					"MainPID":      mainPID,
+				}, nil
+			},
+		},
+	}
+	processes := func() UnitProcesses {
+		res, _, err := conn.UnitProcesses(context.Background(), nil, &UnitProcessesParams{Name: "foo.service"})
+		assert.NoError(t, err)
+		var result UnitProcesses
+		assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+		return result
+	}
+	result := processes()
+	assert.Len(t, result.Processes, 4)
+	assert.Equal(t, ProcessInfo{
+		PID:       100,
+		PPID:      1,
+		Comm:      "foo",
+		Cmdline:   "/usr/bin/foo --daemon",
+		User:      "root",
+		RSS:       1024 * 1024,
+		State:     "S",
+		StartTime: formatTime(usecTime(1700000010 * 1000000)),
+	}, result.Processes[0])
+	assert.Equal(t, "foo (worker)", result.Processes[1].Comm)
+	assert.True(t, result.Processes[1].Zombie)
+	assert.True(t, result.Processes[2].Leftover)
+	assert.Equal(t, "worker", result.Processes[3].Cgroup)
+	assert.False(t, result.Processes[3].Leftover)
+	assert.Equal(t, "├─100 /usr/bin/foo --daemon\n│ ├─101 [foo (worker)] <defunct>\n│ └─103 bar\n└─102 sleep infinity\n", result.Tree)
+	assert.Equal(t, []string{"1 zombie processes, their parent doesn't reap them", "1 processes were left over by exited parents"}, result.Warnings)
+
+	mainPID = 0
+	result = processes()
+	assert.Contains(t, result.Warnings, "4 processes outlived the main process")
+}
+
+func TestUnitProcessesActivating(t *testing.T) {
+	oldProc, oldCgroup := procRoot, cgroupRoot
+	procRoot, cgroupRoot = t.TempDir(), t.TempDir()
+	defer func() { procRoot, cgroupRoot = oldProc, oldCgroup }()
+	assert.NoError(t, os.WriteFile(filepath.Join(procRoot, "stat"), []byte("btime 1700000000\n"), 0o644))
+	writeCgroup(t, cgroupRoot, "system.slice/setup.service", map[string]string{"cgroup.procs": "200\n201\n"})
+	writeProcess(t, procRoot, 200, 1, "setup", "S", "/usr/bin/setup\x00")
+	writeProcess(t, procRoot, 201, 200, "cp", "R", "cp\x00-a\x00")
+	props := map[string]interface{}{
+		"ControlGroup": "/system.slice/setup.service",
+		"Type":         "oneshot",
+		"ActiveState":  "activating",
+		"MainPID":      uint32(0),
+		"ControlPID":   uint32(200),
+	}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				return props, nil
+			},
+		},
+	}
+	processes := func() UnitProcesses {
+		res, _, err := conn.UnitProcesses(context.Background(), nil, &UnitProcessesParams{Name: "setup.service"})
+		assert.NoError(t, err)
+		var result UnitProcesses
+		assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+		return result
+	}
+	// the oneshot runs ExecStart as control process, so there is no main
+	// process yet
+	result := processes()
+	assert.Len(t, result.Processes, 2)
+	for _, proc := range result.Processes {
+		assert.False(t, proc.Leftover, proc.PID)
+	}
+	assert.Empty(t, result.Warnings)
+
+	// a forking service without PIDFile has no known main process
+	props["Type"], props["ActiveState"], props["ControlPID"] = "forking", "active", uint32(0)
+	result = processes()
+	assert.Empty(t, result.Warnings)
+
+	// after the oneshot finished, the remaining processes are left over
+	props["Type"], props["ActiveState"] = "oneshot", "active"
+	result = processes()
+	assert.Equal(t, []string{"2 processes outlived the main process"}, result.Warnings)
+}
@@ -0,0 +1,189 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// kinds of values of the resource control properties
+const (
+	kindSize     = "size"
+	kindPercent  = "percent"
+	kindWeight   = "weight"
+	kindCount    = "count"
+	kindTimeSpan = "timespan"
+)
+
+// resourceProperty maps a setting of systemd.resource-control(5) to the
+// property on the bus
+type resourceProperty struct {
+	kind string
+	// name of the property on the bus if it differs from the setting
+	dbusName string
+}
+
+var resourceProperties = map[string]resourceProperty{
+	"MemoryMax":         {kind: kindSize},
+	"MemoryHigh":        {kind: kindSize},
+	"MemoryLow":         {kind: kindSize},
+	"MemoryMin":         {kind: kindSize},
+	"MemorySwapMax":     {kind: kindSize},
+	"CPUQuota":          {kind: kindPercent, dbusName: "CPUQuotaPerSecUSec"},
+	"CPUQuotaPeriodSec": {kind: kindTimeSpan, dbusName: "CPUQuotaPeriodUSec"},
+	"CPUWeight":         {kind: kindWeight},
+	"StartupCPUWeight":  {kind: kindWeight},
+	"IOWeight":          {kind: kindWeight},
+	"StartupIOWeight":   {kind: kindWeight},
+	"TasksMax":          {kind: kindCount},
+	"RuntimeMaxSec":     {kind: kindTimeSpan, dbusName: "RuntimeMaxUSec"},
+}
+
+// ValidResourceProperties returns the properties which can be set with
+// SetUnitProperties
+func ValidResourceProperties() []string {
+	return slices.Sorted(maps.Keys(resourceProperties))
+}
+
+// resourcePropertyValue converts the value of the setting to the typed
+// property on the bus
+func resourcePropertyValue(name, value string) (dbus.Property, error) {
+	prop, ok := resourceProperties[name]
+	if !ok {
+		return dbus.Property{}, fmt.Errorf("unsupported property %s, valid properties are: %v", name, ValidResourceProperties())
+	}
+	dbusName := prop.dbusName
+	if dbusName == "" {
+		dbusName = name
+	}
+	var val uint64
+	var err error
+	switch prop.kind {
+	case kindSize:
+		val, err = ParseSize(value)
+	case kindPercent:
+		// the quota is stored as cpu time per second, an empty value or
+		// infinity removes it
+		if value == "" || value == "infinity" {
+			val = Infinity
+			break
+		}
+		var percent float64
+		percent, err = ParsePercent(value)
+		if err == nil && percent == 0 {
+			err = fmt.Errorf("%s must be greater than 0%%", name)
+		}
+		val = uint64(percent * 10000)
+	case kindWeight:
+		val, err = parseWeight(name, value)
+	case kindCount:
+		if value == "infinity" {
+			val = Infinity
+			break
+		}
+		if strings.HasSuffix(value, "%") {
+			// a percentage of the system limit is set as fraction of
+			// UINT32_MAX in the Scale property
+			var scale uint32
+			if scale, err = parseScale(value); err != nil {
+				return dbus.Property{}, fmt.Errorf("invalid value for %s: %w", name, err)
+			}
+			return dbus.Property{Name: dbusName + "Scale", Value: godbus.MakeVariant(scale)}, nil
+		}
+		val, err = strconv.ParseUint(value, 10, 64)
+	case kindTimeSpan:
+		val, err = ParseTimeSpan(value)
+	}
+	if err != nil {
+		return dbus.Property{}, fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+	return dbus.Property{Name: dbusName, Value: godbus.MakeVariant(val)}, nil
+}
+
+// parseWeight parses a weight in the range of 1 to 10000, see
+// systemd.resource-control(5). The CPU weights take idle as well, which is
+// 0 on the bus.
+func parseWeight(name, value string) (uint64, error) {
+	if value == "idle" && strings.HasSuffix(name, "CPUWeight") {
+		return 0, nil
+	}
+	weight, err := strconv.ParseUint(value, 10, 64)
+	if err == nil && (weight < 1 || weight > 10000) {
+		err = fmt.Errorf("%s must be between 1 and 10000, got %d", name, weight)
+	}
+	return weight, err
+}
+
+// parseScale parses a percentage up to 100% into a fraction of UINT32_MAX
+// as used by the Scale properties like TasksMaxScale
+func parseScale(value string) (uint32, error) {
+	percent, err := ParsePercent(value)
+	if err != nil {
+		return 0, err
+	}
+	if percent > 100 {
+		return 0, fmt.Errorf("percentage must not be greater than 100%%: %s", value)
+	}
+	return uint32(math.Round(percent / 100 * math.MaxUint32)), nil
+}
+
+type SetPropertiesParams struct {
+	Name       string            `json:"name" jsonschema:"Exact name of the unit, like foo.service."`
+	Properties map[string]string `json:"properties" jsonschema:"Properties to set with their values. Sizes for the memory settings like 512M, 2G or infinity. CPUQuota as percentage of one CPU like 50% or 200%. Weights between 1 and 10000, CPUWeight also idle. TasksMax as number, percentage of the system limit like 50% or infinity. Time spans like 30s or 5min."`
+	Runtime    bool              `json:"runtime,omitempty" jsonschema:"Only change the properties until the next reboot, instead of persistent."`
+}
+
+type SetPropertiesResult struct {
+	Unit       string            `json:"unit"`
+	Runtime    bool              `json:"runtime"`
+	Properties map[string]string `json:"properties"`
+	Values     map[string]uint64 `json:"values"`
+}
+
+// set resource control properties of a unit
+func (conn *Connection) SetUnitProperties(ctx context.Context, req *mcp.CallToolRequest, params *SetPropertiesParams) (*mcp.CallToolResult, any, error) {
+	if params.Name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	if len(params.Properties) == 0 {
+		return nil, nil, fmt.Errorf("no properties given, valid properties are: %v", ValidResourceProperties())
+	}
+	result := SetPropertiesResult{
+		Unit:       params.Name,
+		Runtime:    params.Runtime,
+		Properties: params.Properties,
+		Values:     make(map[string]uint64),
+	}
+	props := []dbus.Property{}
+	for _, name := range slices.Sorted(maps.Keys(params.Properties)) {
+		prop, err := resourcePropertyValue(name, params.Properties[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		props = append(props, prop)
+		result.Values[prop.Name] = prop.Value.Value().(uint64)
+	}
+	if err := conn.dbus.SetUnitPropertiesContext(ctx, params.Name, params.Runtime, props...); err != nil {
+		return nil, nil, fmt.Errorf("couldn't set properties of %s: %w", params.Name, err)
+	}
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,75 @@
+package systemd
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUnitProperties(t *testing.T) {
+	var got []dbus.Property
+	var gotRuntime bool
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			setUnitProperties: func(name string, runtime bool, properties ...dbus.Property) error {
+				got = properties
+				gotRuntime = runtime
+				return nil
+			},
+		},
+	}
+	_, _, err := conn.SetUnitProperties(context.Background(), nil, &SetPropertiesParams{
+		Name: "foo.service",
+		Properties: map[string]string{
+			"MemoryMax": "512M",
+			"CPUQuota":  "50%",
+			"TasksMax":  "infinity",
+			"IOWeight":  "200",
+		},
+		Runtime: true,
+	})
+	assert.NoError(t, err)
+	assert.True(t, gotRuntime)
+	values := map[string]uint64{}
+	for _, prop := range got {
+		values[prop.Name] = prop.Value.Value().(uint64)
+	}
+	assert.Equal(t, map[string]uint64{
+		"CPUQuotaPerSecUSec": 500000,
+		"IOWeight":           200,
+		"MemoryMax":          512 << 20,
+		"TasksMax":           Infinity,
+	}, values)
+
+	for msg, props := range map[string]map[string]string{
+		"unsupported property": {"Nice": "5"},
+		"between 1 and 10000":  {"CPUWeight": "0"},
+		"invalid percentage":   {"CPUQuota": "50"},
+		"invalid size":         {"MemoryMax": "lots"},
+		"greater than 100%":    {"TasksMax": "150%"},
+		"invalid syntax":       {"IOWeight": "idle"},
+	} {
+		_, _, err := conn.SetUnitProperties(context.Background(), nil, &SetPropertiesParams{Name: "foo.service", Properties: props})
+		assert.ErrorContains(t, err, msg)
+	}
+}
+
+func TestResourcePropertyValue(t *testing.T) {
+	prop, err := resourcePropertyValue("TasksMax", "50%")
+	assert.NoError(t, err)
+	assert.Equal(t, "TasksMaxScale", prop.Name)
+	assert.Equal(t, uint32(math.MaxUint32/2+1), prop.Value.Value())
+	prop, err = resourcePropertyValue("TasksMax", "100%")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(math.MaxUint32), prop.Value.Value())
+	prop, err = resourcePropertyValue("CPUWeight", "idle")
+	assert.NoError(t, err)
+	assert.Equal(t, "CPUWeight", prop.Name)
+	assert.Equal(t, uint64(0), prop.Value.Value())
+	prop, err = resourcePropertyValue("StartupCPUWeight", "idle")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), prop.Value.Value())
+}
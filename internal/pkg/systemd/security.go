@@ -0,0 +1,422 @@
+package systemd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// capabilities which allow to break out of the sandbox or to take over the
+// system, by their number in linux/capability.h
+var dangerousCapabilities = map[int]string{
+	1:  "CAP_DAC_OVERRIDE",
+	2:  "CAP_DAC_READ_SEARCH",
+	3:  "CAP_FOWNER",
+	6:  "CAP_SETGID",
+	7:  "CAP_SETUID",
+	12: "CAP_NET_ADMIN",
+	16: "CAP_SYS_MODULE",
+	17: "CAP_SYS_RAWIO",
+	19: "CAP_SYS_PTRACE",
+	21: "CAP_SYS_ADMIN",
+	22: "CAP_SYS_BOOT",
+	25: "CAP_SYS_TIME",
+	27: "CAP_MKNOD",
+	31: "CAP_SETFCAP",
+	38: "CAP_PERFMON",
+	39: "CAP_BPF",
+}
+
+// securityCheck assesses a single setting, the badness is between 0 for
+// a safe setting and 1 for the default without any protection
+type securityCheck struct {
+	setting     string
+	description string
+	weight      float64
+	assess      func(props map[string]interface{}) (badness float64, current string)
+	remediation string
+	// risk tells what the remediation may break
+	risk string
+}
+
+// boolCheck returns a check for a setting which protects when set
+func boolCheck(setting, description string, weight float64, risk string) securityCheck {
+	return securityCheck{
+		setting:     setting,
+		description: description,
+		weight:      weight,
+		assess: func(props map[string]interface{}) (float64, string) {
+			if val, _ := props[setting].(bool); val {
+				return 0, "yes"
+			}
+			return 1, "no"
+		},
+		remediation: setting + "=yes",
+		risk:        risk,
+	}
+}
+
+// levelCheck returns a check for a setting with string values, the values
+// not in the map have the badness 1
+func levelCheck(setting, description string, weight float64, levels map[string]float64, remediation, risk string) securityCheck {
+	return securityCheck{
+		setting:     setting,
+		description: description,
+		weight:      weight,
+		assess: func(props map[string]interface{}) (float64, string) {
+			val, _ := props[setting].(string)
+			if badness, ok := levels[val]; ok {
+				return badness, val
+			}
+			return 1, val
+		},
+		remediation: remediation,
+		risk:        risk,
+	}
+}
+
+// filterList returns a property with the signature (bas), which is used for
+// allow and deny lists
+func filterList(props map[string]interface{}, key string) (allowList bool, list []string) {
+	val, _ := props[key].([]interface{})
+	if len(val) != 2 {
+		return false, nil
+	}
+	allowList, _ = val[0].(bool)
+	list, _ = val[1].([]string)
+	return allowList, list
+}
+
+// securityChecks is the weighted model of the assessment, the weights
+// follow the ones of systemd-analyze security
+var securityChecks = []securityCheck{
+	{
+		setting:     "User",
+		description: "Service runs as root, a compromise gives full access to the system",
+		weight:      2000,
+		assess: func(props map[string]interface{}) (float64, string) {
+			if dynamic, _ := props["DynamicUser"].(bool); dynamic {
+				return 0, "DynamicUser=yes"
+			}
+			user, _ := props["User"].(string)
+			if user == "" || user == "root" || user == "0" {
+				return 1, "root"
+			}
+			return 0, user
+		},
+		remediation: "DynamicUser=yes",
+		risk:        "The service gets a transient user, files it needs to write have to move to StateDirectory= or similar",
+	},
+	boolCheck("NoNewPrivileges", "Processes may acquire new privileges with setuid binaries", 1000, "Breaks services which run setuid binaries like sudo or ping"),
+	levelCheck("ProtectSystem", "Service may modify the operating system in /usr, /boot and /etc", 1000,
+		map[string]float64{"strict": 0, "full": 0.33, "yes": 0.66, "true": 0.66}, "ProtectSystem=strict",
+		"The whole file system is read-only, the paths the service writes to need ReadWritePaths= or StateDirectory="),
+	levelCheck("ProtectHome", "Service may access the home directories", 1000,
+		map[string]float64{"yes": 0, "true": 0, "tmpfs": 0, "read-only": 0.5}, "ProtectHome=yes",
+		"Breaks services which access files in /home, /root or /run/user"),
+	boolCheck("PrivateTmp", "Service shares /tmp with the other processes", 1000, "Files in /tmp aren't shared with other services anymore"),
+	boolCheck("PrivateDevices", "Service may access the hardware devices", 1000, "Breaks services which access hardware like disks, serial ports or GPUs"),
+	boolCheck("PrivateUsers", "Service shares the user namespace of the host", 1000, "Files of other users appear as owned by nobody, breaks services which change the owner of files"),
+	boolCheck("ProtectKernelTunables", "Service may change kernel tunables in /proc/sys and /sys", 1000, "Breaks services which set sysctls or write to /sys"),
+	boolCheck("ProtectKernelModules", "Service may load kernel modules", 1000, "Breaks services which load kernel modules"),
+	boolCheck("ProtectKernelLogs", "Service may read the kernel log ring buffer", 1000, "Breaks services which read the kernel log"),
+	boolCheck("ProtectControlGroups", "Service may modify the control group hierarchy", 1000, "Breaks container managers and services with delegated control groups"),
+	boolCheck("ProtectClock", "Service may change the system clock", 1000, "Breaks time synchronization services"),
+	boolCheck("ProtectHostname", "Service may change the hostname", 50, "Breaks services which set the hostname"),
+	boolCheck("RestrictSUIDSGID", "Service may create setuid and setgid files", 1000, "Breaks services which create setuid files like package managers"),
+	boolCheck("RestrictRealtime", "Service may acquire realtime scheduling and starve the system", 500, "Breaks audio and other services which need realtime scheduling"),
+	boolCheck("LockPersonality", "Service may change the execution domain", 100, "Breaks emulators which change the execution domain"),
+	boolCheck("MemoryDenyWriteExecute", "Service may create writable and executable memory mappings", 100, "Breaks JIT compilers like the ones of Java, Node.js or PCRE"),
+	boolCheck("RemoveIPC", "IPC objects of the service user are kept after it stopped", 100, "Shared memory and semaphores of the service user are removed when it stops"),
+	levelCheck("ProtectProc", "Service may see the processes of other users in /proc", 1000,
+		map[string]float64{"invisible": 0, "noaccess": 0, "ptraceable": 0.5}, "ProtectProc=invisible",
+		"Breaks monitoring services which inspect the processes of other users"),
+	levelCheck("ProcSubset", "Service may access the system information in /proc", 500,
+		map[string]float64{"pid": 0}, "ProcSubset=pid",
+		"Breaks services which read /proc/meminfo, /proc/cpuinfo or other system information"),
+	{
+		setting:     "RestrictNamespaces",
+		description: "Service may create new namespaces, which increases the attack surface of the kernel",
+		weight:      1000,
+		assess: func(props map[string]interface{}) (float64, string) {
+			allowed, ok := props["RestrictNamespaces"].(uint64)
+			if ok && allowed == 0 {
+				return 0, "yes"
+			}
+			return 1, "no"
+		},
+		remediation: "RestrictNamespaces=yes",
+		risk:        "Breaks sandboxing tools, browsers and container runtimes",
+	},
+	{
+		setting:     "RestrictAddressFamilies",
+		description: "Service may use all socket address families, like AF_PACKET for raw packets",
+		weight:      1500,
+		assess: func(props map[string]interface{}) (float64, string) {
+			allowList, families := filterList(props, "RestrictAddressFamilies")
+			if !allowList {
+				if len(families) == 0 {
+					return 1, "all"
+				}
+				return 0.5, "~" + strings.Join(families, " ")
+			}
+			if slices.Contains(families, "AF_PACKET") || slices.Contains(families, "AF_NETLINK") {
+				return 0.25, strings.Join(families, " ")
+			}
+			return 0, strings.Join(families, " ")
+		},
+		remediation: "RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6",
+		risk:        "Breaks services which use netlink, raw packets or bluetooth",
+	},
+	{
+		setting:     "SystemCallFilter",
+		description: "Service may use all system calls",
+		weight:      1000,
+		assess: func(props map[string]interface{}) (float64, string) {
+			allowList, calls := filterList(props, "SystemCallFilter")
+			switch {
+			case allowList && len(calls) > 0:
+				return 0, fmt.Sprintf("%d allowed system calls", len(calls))
+			case !allowList && len(calls) > 0:
+				return 0.5, fmt.Sprintf("%d denied system calls", len(calls))
+			}
+			return 1, "all"
+		},
+		remediation: "SystemCallFilter=@system-service\nSystemCallFilter=~@privileged @resources",
+		risk:        "The service is killed when it uses a filtered system call, test all of its features",
+	},
+	{
+		setting:     "SystemCallArchitectures",
+		description: "Service may use system calls of foreign architectures, which bypasses the system call filter",
+		weight:      1000,
+		assess: func(props map[string]interface{}) (float64, string) {
+			archs := stringSlice(props, "SystemCallArchitectures")
+			if slices.Equal(archs, []string{"native"}) {
+				return 0, "native"
+			}
+			if len(archs) == 0 {
+				return 1, "all"
+			}
+			return 0.5, strings.Join(archs, " ")
+		},
+		remediation: "SystemCallArchitectures=native",
+		risk:        "Breaks 32 bit binaries on 64 bit hosts",
+	},
+	{
+		setting:     "CapabilityBoundingSet",
+		description: "Service keeps capabilities which allow to take over the system",
+		weight:      1500,
+		assess: func(props map[string]interface{}) (float64, string) {
+			capSet, ok := props["CapabilityBoundingSet"].(uint64)
+			if !ok {
+				capSet = math.MaxUint64
+			}
+			kept := []string{}
+			for bit, name := range dangerousCapabilities {
+				if capSet&(1<<bit) != 0 {
+					kept = append(kept, name)
+				}
+			}
+			slices.Sort(kept)
+			if len(kept) == 0 {
+				return 0, fmt.Sprintf("%d capabilities", bits.OnesCount64(capSet))
+			}
+			return float64(len(kept)) / float64(len(dangerousCapabilities)), strings.Join(kept, " ")
+		},
+		remediation: "CapabilityBoundingSet=",
+		risk:        "Drops all capabilities, breaks services which bind ports below 1024, change the owner of files or need other privileges",
+	},
+	{
+		setting:     "AmbientCapabilities",
+		description: "Service gets ambient capabilities, also when not running as root",
+		weight:      500,
+		assess: func(props map[string]interface{}) (float64, string) {
+			if ambient, _ := props["AmbientCapabilities"].(uint64); ambient != 0 {
+				return 1, fmt.Sprintf("%d capabilities", bits.OnesCount64(ambient))
+			}
+			return 0, "none"
+		},
+		remediation: "AmbientCapabilities=",
+		risk:        "Breaks services which rely on the capabilities to run without root",
+	},
+	{
+		setting:     "IPAddressDeny",
+		description: "Service may connect to any IP address",
+		weight:      500,
+		assess: func(props map[string]interface{}) (float64, string) {
+			if deny, _ := props["IPAddressDeny"].([][]interface{}); len(deny) > 0 {
+				return 0, fmt.Sprintf("%d denied ranges", len(deny))
+			}
+			return 1, "none"
+		},
+		remediation: "IPAddressDeny=any\nIPAddressAllow=localhost",
+		risk:        "Only connections to localhost work, breaks services which use the network",
+	},
+	{
+		setting:     "UMask",
+		description: "Files created by the service are readable or writable by others",
+		weight:      100,
+		assess: func(props map[string]interface{}) (float64, string) {
+			umask, ok := props["UMask"].(uint32)
+			if !ok {
+				umask = 0o022
+			}
+			current := fmt.Sprintf("%04o", umask)
+			switch {
+			case umask&0o002 == 0:
+				return 1, current
+			case umask&0o004 == 0:
+				return 0.5, current
+			}
+			return 0, current
+		},
+		remediation: "UMask=0077",
+		risk:        "Files created by the service aren't readable by other users anymore",
+	},
+}
+
+// Finding is a setting of the unit which weakens its sandbox
+type Finding struct {
+	Setting     string  `json:"setting"`
+	Description string  `json:"description"`
+	Current     string  `json:"current"`
+	Badness     float64 `json:"badness"`
+	Weight      float64 `json:"weight"`
+	Remediation string  `json:"remediation"`
+	Risk        string  `json:"risk"`
+}
+
+type SecurityAssessment struct {
+	Unit     string    `json:"unit"`
+	Exposure float64   `json:"exposure"`
+	Rating   string    `json:"rating"`
+	Findings []Finding `json:"findings"`
+	DropIn   string    `json:"drop_in,omitempty"`
+}
+
+// exposureRating returns the rating of the exposure level as
+// systemd-analyze security
+func exposureRating(exposure float64) string {
+	switch {
+	case exposure <= 0:
+		return "PERFECT"
+	case exposure < 2:
+		return "SAFE"
+	case exposure < 5:
+		return "OK"
+	case exposure < 7:
+		return "MEDIUM"
+	case exposure < 9:
+		return "EXPOSED"
+	}
+	return "UNSAFE"
+}
+
+// assessSecurity scores the properties of the unit, the exposure is
+// between 0 for a fully sandboxed and 10 for an unprotected service
+func assessSecurity(name string, props map[string]interface{}) SecurityAssessment {
+	assessment := SecurityAssessment{Unit: name, Findings: []Finding{}}
+	var weightSum, badnessSum float64
+	for _, check := range securityChecks {
+		badness, current := check.assess(props)
+		weightSum += check.weight
+		badnessSum += badness * check.weight
+		if badness == 0 {
+			continue
+		}
+		assessment.Findings = append(assessment.Findings, Finding{
+			Setting:     check.setting,
+			Description: check.description,
+			Current:     current,
+			Badness:     math.Round(badness*100) / 100,
+			Weight:      check.weight,
+			Remediation: check.remediation,
+			Risk:        check.risk,
+		})
+	}
+	assessment.Exposure = math.Round(badnessSum/weightSum*100) / 10
+	assessment.Rating = exposureRating(assessment.Exposure)
+	slices.SortStableFunc(assessment.Findings, func(a, b Finding) int {
+		return cmp.Compare(b.Badness*b.Weight, a.Badness*a.Weight)
+	})
+	if len(assessment.Findings) > 0 {
+		// the combined settings break most services, so they are only a
+		// template to pick the remediations from
+		lines := []string{
+			"# Starting point only, don't apply it blindly: every setting may break",
+			"# the service, check the risk of each finding and test the service.",
+			"[Service]",
+		}
+		for _, finding := range assessment.Findings {
+			lines = append(lines, finding.Remediation)
+		}
+		assessment.DropIn = strings.Join(lines, "\n") + "\n"
+	}
+	return assessment
+}
+
+type SecurityAuditParams struct {
+	Name string `json:"name,omitempty" jsonschema:"Exact name of the service to audit, if not set all running services are audited."`
+}
+
+// audit the sandboxing of services
+func (conn *Connection) SecurityAudit(ctx context.Context, req *mcp.CallToolRequest, params *SecurityAuditParams) (*mcp.CallToolResult, any, error) {
+	names := []string{}
+	if params.Name != "" {
+		if UnitType(params.Name) != "service" {
+			return nil, nil, fmt.Errorf("only services can be audited, got: %s", params.Name)
+		}
+		names = append(names, params.Name)
+	} else {
+		units, err := conn.dbus.ListUnitsByPatternsContext(ctx, []string{"running"}, []string{"*.service"})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, unit := range units {
+			names = append(names, unit.Name)
+		}
+	}
+	assessments := []SecurityAssessment{}
+	for _, name := range names {
+		props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if loadState, _ := props["LoadState"].(string); loadState == "not-found" {
+			return nil, nil, fmt.Errorf("unit %s not found", name)
+		}
+		assessments = append(assessments, assessSecurity(name, props))
+	}
+	// the most exposed services first
+	slices.SortStableFunc(assessments, func(a, b SecurityAssessment) int {
+		if c := cmp.Compare(b.Exposure, a.Exposure); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Unit, b.Unit)
+	})
+	txtContentList := []mcp.Content{}
+	for _, assessment := range assessments {
+		jsonByte, err := json.Marshal(assessment)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	if len(txtContentList) == 0 {
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: "no running services found",
+		})
+	}
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+	}, nil, nil
+}
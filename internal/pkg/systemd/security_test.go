@@ -0,0 +1,115 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func hardenedProps() map[string]interface{} {
+	return map[string]interface{}{
+		"DynamicUser":             true,
+		"NoNewPrivileges":         true,
+		"ProtectSystem":           "strict",
+		"ProtectHome":             "yes",
+		"PrivateTmp":              true,
+		"PrivateDevices":          true,
+		"PrivateUsers":            true,
+		"ProtectKernelTunables":   true,
+		"ProtectKernelModules":    true,
+		"ProtectKernelLogs":       true,
+		"ProtectControlGroups":    true,
+		"ProtectClock":            true,
+		"ProtectHostname":         true,
+		"RestrictSUIDSGID":        true,
+		"RestrictRealtime":        true,
+		"LockPersonality":         true,
+		"MemoryDenyWriteExecute":  true,
+		"RemoveIPC":               true,
+		"ProtectProc":             "invisible",
+		"ProcSubset":              "pid",
+		"RestrictNamespaces":      uint64(0),
+		"RestrictAddressFamilies": []interface{}{true, []string{"AF_UNIX", "AF_INET"}},
+		"SystemCallFilter":        []interface{}{true, []string{"read", "write"}},
+		"SystemCallArchitectures": []string{"native"},
+		"CapabilityBoundingSet":   uint64(0),
+		"AmbientCapabilities":     uint64(0),
+		"IPAddressDeny":           [][]interface{}{{int32(2), []byte{0, 0, 0, 0}, uint32(0)}},
+		"UMask":                   uint32(0o077),
+	}
+}
+
+func TestAssessSecurity(t *testing.T) {
+	assessment := assessSecurity("hardened.service", hardenedProps())
+	assert.Equal(t, 0.0, assessment.Exposure)
+	assert.Equal(t, "PERFECT", assessment.Rating)
+	assert.Empty(t, assessment.Findings)
+	assert.Empty(t, assessment.DropIn)
+
+	assessment = assessSecurity("default.service", map[string]interface{}{})
+	assert.Greater(t, assessment.Exposure, 9.0)
+	assert.Equal(t, "UNSAFE", assessment.Rating)
+	assert.Equal(t, "User", assessment.Findings[0].Setting)
+	assert.Equal(t, "root", assessment.Findings[0].Current)
+	assert.Contains(t, assessment.DropIn, "[Service]\nDynamicUser=yes\n")
+	assert.True(t, strings.HasPrefix(assessment.DropIn, "# Starting point only"))
+	for _, finding := range assessment.Findings {
+		assert.NotEmpty(t, finding.Risk, finding.Setting)
+	}
+
+	props := hardenedProps()
+	props["DynamicUser"] = false
+	props["User"] = "nobody"
+	props["ProtectSystem"] = "full"
+	props["CapabilityBoundingSet"] = uint64(1<<21 | 1<<10)
+	props["SystemCallFilter"] = []interface{}{false, []string{"reboot"}}
+	assessment = assessSecurity("partial.service", props)
+	assert.Equal(t, "SAFE", assessment.Rating)
+	settings := []string{}
+	for _, finding := range assessment.Findings {
+		settings = append(settings, finding.Setting)
+	}
+	assert.Equal(t, []string{"SystemCallFilter", "ProtectSystem", "CapabilityBoundingSet"}, settings)
+	assert.Equal(t, "CAP_SYS_ADMIN", assessment.Findings[2].Current)
+	assert.Contains(t, assessment.Findings[1].Risk, "ReadWritePaths=")
+	assert.Contains(t, assessment.DropIn, "[Service]\nSystemCallFilter=@system-service\nSystemCallFilter=~@privileged @resources\nProtectSystem=strict\nCapabilityBoundingSet=\n", assessment.DropIn)
+}
+
+func TestSecurityAudit(t *testing.T) {
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			listUnitsByPatterns: func(patterns, states []string) ([]dbus.UnitStatus, error) {
+				assert.Equal(t, []string{"*.service"}, patterns)
+				assert.Equal(t, []string{"running"}, states)
+				return []dbus.UnitStatus{{Name: "hardened.service"}, {Name: "default.service"}}, nil
+			},
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				if unitName == "hardened.service" {
+					return hardenedProps(), nil
+				}
+				if unitName == "missing.service" {
+					return map[string]interface{}{"LoadState": "not-found"}, nil
+				}
+				return map[string]interface{}{"LoadState": "loaded"}, nil
+			},
+		},
+	}
+	res, _, err := conn.SecurityAudit(context.Background(), nil, &SecurityAuditParams{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Content, 2)
+	var assessment SecurityAssessment
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &assessment))
+	assert.Equal(t, "default.service", assessment.Unit)
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[1].(*mcp.TextContent).Text), &assessment))
+	assert.Equal(t, "hardened.service", assessment.Unit)
+
+	_, _, err = conn.SecurityAudit(context.Background(), nil, &SecurityAuditParams{Name: "missing.service"})
+	assert.Error(t, err)
+	_, _, err = conn.SecurityAudit(context.Background(), nil, &SecurityAuditParams{Name: "foo.socket"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,183 @@
+package systemd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// signalNames are the names of the linux signals
+var signalNames = map[int32]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	10: "SIGUSR1",
+	11: "SIGSEGV",
+	12: "SIGUSR2",
+	13: "SIGPIPE",
+	14: "SIGALRM",
+	15: "SIGTERM",
+	16: "SIGSTKFLT",
+	17: "SIGCHLD",
+	18: "SIGCONT",
+	19: "SIGSTOP",
+	20: "SIGTSTP",
+	21: "SIGTTIN",
+	22: "SIGTTOU",
+	23: "SIGURG",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+	26: "SIGVTALRM",
+	27: "SIGPROF",
+	28: "SIGWINCH",
+	29: "SIGIO",
+	30: "SIGPWR",
+	31: "SIGSYS",
+}
+
+// exitStatusNames are the names of the generic, LSB and systemd specific
+// exit codes, see systemd.exec(5)
+var exitStatusNames = map[int32]string{
+	0:   "SUCCESS",
+	1:   "FAILURE",
+	2:   "INVALIDARGUMENT",
+	3:   "NOTIMPLEMENTED",
+	4:   "NOPERMISSION",
+	5:   "NOTINSTALLED",
+	6:   "NOTCONFIGURED",
+	7:   "NOTRUNNING",
+	200: "CHDIR",
+	201: "NICE",
+	202: "FDS",
+	203: "EXEC",
+	204: "MEMORY",
+	205: "LIMITS",
+	206: "OOM_ADJUST",
+	207: "SIGNAL_MASK",
+	208: "STDIN",
+	209: "STDOUT",
+	210: "CHROOT",
+	211: "IOPRIO",
+	212: "TIMERSLACK",
+	213: "SECUREBITS",
+	214: "SETSCHEDULER",
+	215: "CPUAFFINITY",
+	216: "GROUP",
+	217: "USER",
+	218: "CAPABILITIES",
+	219: "CGROUP",
+	220: "SETSID",
+	221: "CONFIRM",
+	222: "STDERR",
+	224: "PAM",
+	225: "NETWORK",
+	226: "NAMESPACE",
+	227: "NO_NEW_PRIVILEGES",
+	228: "SECCOMP",
+	229: "SELINUX_CONTEXT",
+	230: "PERSONALITY",
+	231: "APPARMOR_PROFILE",
+	232: "ADDRESS_FAMILIES",
+	233: "RUNTIME_DIRECTORY",
+	235: "CHOWN",
+	236: "SMACK_PROCESS_LABEL",
+	237: "KEYRING",
+	238: "STATE_DIRECTORY",
+	239: "CACHE_DIRECTORY",
+	240: "LOGS_DIRECTORY",
+	241: "CONFIGURATION_DIRECTORY",
+	242: "NUMA_POLICY",
+	243: "CREDENTIALS",
+	245: "BPF",
+}
+
+// SignalName returns the name of the signal, e.g. SIGKILL for 9
+func SignalName(signal int32) string {
+	if name, ok := signalNames[signal]; ok {
+		return name
+	}
+	return fmt.Sprintf("SIG%d", signal)
+}
+
+// realtime signals are SIGRTMIN+n up to SIGRTMAX
+const (
+	sigRTMin = 34
+	sigRTMax = 64
+)
+
+// ParseSignal parses a signal by its name like SIGTERM or TERM, as
+// SIGRTMIN+n or by its number
+func ParseSignal(name string) (int32, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if num, err := strconv.ParseInt(name, 10, 32); err == nil {
+		if num < 1 || num > sigRTMax {
+			return 0, fmt.Errorf("invalid signal number: %d", num)
+		}
+		return int32(num), nil
+	}
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	for signal, signalName := range signalNames {
+		if signalName == name {
+			return signal, nil
+		}
+	}
+	for prefix, base := range map[string]int32{"SIGRTMIN": sigRTMin, "SIGRTMAX": sigRTMax} {
+		rest, found := strings.CutPrefix(name, prefix)
+		if !found {
+			continue
+		}
+		offset := int64(0)
+		if rest != "" {
+			var err error
+			if offset, err = strconv.ParseInt(rest, 10, 32); err != nil {
+				return 0, fmt.Errorf("invalid signal: %s", name)
+			}
+		}
+		if signal := int64(base) + offset; signal >= sigRTMin && signal <= sigRTMax {
+			return int32(signal), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid signal: %s", name)
+}
+
+// ExitStatusName returns the name of the exit status, e.g. FAILURE for 1
+func ExitStatusName(status int32) string {
+	if name, ok := exitStatusNames[status]; ok {
+		return name
+	}
+	return strconv.Itoa(int(status))
+}
+
+// ExitCodeName returns the name of the ExecMainCode property, which has
+// the values of siginfo_t.si_code
+func ExitCodeName(code int32) string {
+	switch code {
+	case 1:
+		return "exited"
+	case 2:
+		return "killed"
+	case 3:
+		return "dumped"
+	}
+	return ""
+}
+
+// DecodeExitStatus returns the name of the status, which is an exit
+// status if the process exited or the signal which killed it
+func DecodeExitStatus(code int32, status int32) string {
+	switch ExitCodeName(code) {
+	case "exited":
+		return ExitStatusName(status)
+	case "killed", "dumped":
+		return SignalName(status)
+	}
+	return ""
+}
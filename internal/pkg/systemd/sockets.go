@@ -0,0 +1,130 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ListSocketsParams struct {
+	Service string `json:"service,omitempty" jsonschema:"Only list the sockets which activate this service, like foo.service."`
+	All     bool   `json:"all,omitempty" jsonschema:"Also list the inactive sockets."`
+}
+
+type ListenAddress struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+type SocketInfo struct {
+	Socket       string          `json:"socket"`
+	ActiveState  string          `json:"active_state"`
+	SubState     string          `json:"sub_state"`
+	Result       string          `json:"result,omitempty"`
+	Listen       []ListenAddress `json:"listen"`
+	Triggers     []string        `json:"triggers"`
+	Accept       bool            `json:"accept"`
+	NConnections uint32          `json:"n_connections"`
+	NAccepted    uint32          `json:"n_accepted"`
+	NRefused     uint32          `json:"n_refused"`
+}
+
+// listenAddresses returns the Listen property, which has the signature
+// a(ss) with the type and the address
+func listenAddresses(props map[string]interface{}) []ListenAddress {
+	ret := []ListenAddress{}
+	listen, _ := props["Listen"].([][]interface{})
+	for _, elem := range listen {
+		if len(elem) != 2 {
+			continue
+		}
+		addr := ListenAddress{}
+		addr.Type, _ = elem[0].(string)
+		addr.Address, _ = elem[1].(string)
+		ret = append(ret, addr)
+	}
+	return ret
+}
+
+// socketInfo reads the addresses and the connection counters of the socket
+func (conn *Connection) socketInfo(ctx context.Context, name string) (SocketInfo, error) {
+	info := SocketInfo{Socket: name}
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+	if err != nil {
+		return info, err
+	}
+	info.ActiveState, _ = props["ActiveState"].(string)
+	info.SubState, _ = props["SubState"].(string)
+	info.Result, _ = props["Result"].(string)
+	info.Listen = listenAddresses(props)
+	info.Triggers = stringSlice(props, "Triggers")
+	info.Accept, _ = props["Accept"].(bool)
+	info.NConnections, _ = props["NConnections"].(uint32)
+	info.NAccepted, _ = props["NAccepted"].(uint32)
+	info.NRefused, _ = props["NRefused"].(uint32)
+	return info, nil
+}
+
+// list the sockets with their addresses and the services they activate
+func (conn *Connection) ListSockets(ctx context.Context, req *mcp.CallToolRequest, params *ListSocketsParams) (*mcp.CallToolResult, any, error) {
+	var names []string
+	if params.Service != "" {
+		props, err := conn.dbus.GetAllPropertiesContext(ctx, params.Service)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, unit := range stringSlice(props, "TriggeredBy") {
+			if strings.HasSuffix(unit, ".socket") {
+				names = append(names, unit)
+			}
+		}
+		if len(names) == 0 {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Text: fmt.Sprintf("%s isn't activated by a socket", params.Service),
+					},
+				},
+			}, nil, nil
+		}
+	} else {
+		states := []string{"active"}
+		if params.All {
+			states = []string{}
+		}
+		units, err := conn.dbus.ListUnitsByPatternsContext(ctx, states, []string{"*.socket"})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, unit := range units {
+			names = append(names, unit.Name)
+		}
+	}
+	slices.Sort(names)
+	txtContentList := []mcp.Content{}
+	for _, name := range names {
+		info, err := conn.socketInfo(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		jsonByte, err := json.Marshal(info)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	if len(txtContentList) == 0 {
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: "no sockets found",
+		})
+	}
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+	}, nil, nil
+}
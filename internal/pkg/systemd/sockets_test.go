@@ -0,0 +1,58 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListSocketsOfService(t *testing.T) {
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				switch unitName {
+				case "sshd.service":
+					return map[string]interface{}{
+						"TriggeredBy": []string{"sshd.socket", "sshd.path"},
+					}, nil
+				case "sshd.socket":
+					return map[string]interface{}{
+						"ActiveState": "active",
+						"SubState":    "listening",
+						"Listen": [][]interface{}{
+							{"Stream", "0.0.0.0:22"},
+							{"Stream", "[::]:22"},
+						},
+						"Triggers":     []string{"sshd.service"},
+						"NConnections": uint32(1),
+						"NAccepted":    uint32(42),
+						"NRefused":     uint32(3),
+					}, nil
+				}
+				return map[string]interface{}{}, nil
+			},
+		},
+	}
+	res, _, err := conn.ListSockets(context.Background(), nil, &ListSocketsParams{Service: "sshd.service"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Content, 1)
+	var info SocketInfo
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &info))
+	assert.Equal(t, SocketInfo{
+		Socket:       "sshd.socket",
+		ActiveState:  "active",
+		SubState:     "listening",
+		Listen:       []ListenAddress{{Type: "Stream", Address: "0.0.0.0:22"}, {Type: "Stream", Address: "[::]:22"}},
+		Triggers:     []string{"sshd.service"},
+		NConnections: 1,
+		NAccepted:    42,
+		NRefused:     3,
+	}, info)
+
+	res, _, err = conn.ListSockets(context.Background(), nil, &ListSocketsParams{Service: "foo.service"})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo.service isn't activated by a socket", res.Content[0].(*mcp.TextContent).Text)
+}
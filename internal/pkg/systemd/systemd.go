@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
 )
 
 // DbusConnection is an interface that abstracts the dbus connection.
@@ -17,39 +18,73 @@ type DbusConnection interface {
 	RestartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
 	StartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
 	StopUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
-	KillUnitContext(ctx context.Context, name string, signal int32)
+	StartTransientUnitContext(ctx context.Context, name string, mode string, properties []dbus.Property, ch chan<- string) (int, error)
+	KillUnitWithTarget(ctx context.Context, name string, target dbus.Who, signal int32) error
+	ResetFailedUnitContext(ctx context.Context, name string) error
 	EnableUnitFilesContext(ctx context.Context, files []string, runtime bool, force bool) (bool, []dbus.EnableUnitFileChange, error)
 	DisableUnitFilesContext(ctx context.Context, files []string, runtime bool) ([]dbus.DisableUnitFileChange, error)
 	ListUnitFilesContext(ctx context.Context) ([]dbus.UnitFile, error)
+	MaskUnitFilesContext(ctx context.Context, files []string, runtime bool, force bool) ([]dbus.MaskUnitFileChange, error)
+	UnmaskUnitFilesContext(ctx context.Context, files []string, runtime bool) ([]dbus.UnmaskUnitFileChange, error)
+	LinkUnitFilesContext(ctx context.Context, files []string, runtime bool, force bool) ([]dbus.LinkUnitFileChange, error)
+	ReloadContext(ctx context.Context) error
+	SetUnitPropertiesContext(ctx context.Context, name string, runtime bool, properties ...dbus.Property) error
+	FreezeUnit(ctx context.Context, unit string) error
+	ThawUnit(ctx context.Context, unit string) error
+	// methods not wrapped by go-systemd, see systemdConn
+	PresetUnitFilesWithModeContext(ctx context.Context, files []string, mode string, runtime bool, force bool) (bool, []dbus.EnableUnitFileChange, error)
+	PresetAllUnitFilesContext(ctx context.Context, mode string, runtime bool, force bool) ([]dbus.EnableUnitFileChange, error)
+	StartTransientUnitAuxContext(ctx context.Context, name string, mode string, properties []dbus.Property, aux []AuxUnit) (string, error)
+	GetManagerPropertiesContext(ctx context.Context) (map[string]interface{}, error)
+	CleanUnitContext(ctx context.Context, name string, mask []string) error
+	GetUnitFileStateContext(ctx context.Context, name string) (string, error)
+	GetDefaultTargetContext(ctx context.Context) (string, error)
+	SetDefaultTargetContext(ctx context.Context, name string, force bool) ([]dbus.EnableUnitFileChange, error)
+	ReexecuteContext(ctx context.Context) error
 
 	Close()
 }
 
 type Connection struct {
-	rchannel chan string
-	dbus     DbusConnection
+	// jobs are the jobs which were still running when their tool returned
+	jobs    pendingJobs
+	dbus    DbusConnection
+	unitLog UnitLog
+	// user is set for the connection to the user manager
+	user   bool
+	policy *policy.Policy
 }
 
 // opens a new user connection to the dbus
 func NewUser(ctx context.Context) (conn *Connection, err error) {
-	conn = new(Connection)
-	conn.dbus, err = dbus.NewUserConnectionContext(ctx)
+	conn = &Connection{}
+	conn.dbus, err = newSystemdConn(ctx, true)
 	if err != nil {
 		return nil, err
 	}
+	conn.user = true
 	return conn, err
 }
 func NewSystem(ctx context.Context) (conn *Connection, err error) {
-	conn = new(Connection)
-	conn.dbus, err = dbus.NewSystemConnectionContext(ctx)
+	conn = &Connection{}
+	conn.dbus, err = newSystemdConn(ctx, false)
 	if err != nil {
 		return nil, err
 	}
 	return conn, err
 }
 
+// SetPolicy sets the policy which restricts the actions of the tools
+func (conn *Connection) SetPolicy(p *policy.Policy) {
+	conn.policy = p
+}
+
+// GetAllPropertiesContext returns all the properties of the given unit
+func (conn *Connection) GetAllPropertiesContext(ctx context.Context, unitName string) (map[string]interface{}, error) {
+	return conn.dbus.GetAllPropertiesContext(ctx, unitName)
+}
+
 // close the connection
 func (conn *Connection) Close() {
 	conn.dbus.Close()
-	close(conn.rchannel)
 }
@@ -0,0 +1,163 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/sys/unix"
+)
+
+// monotonicNow returns the current value of the monotonic clock, on which
+// the monotonic timestamps of systemd are based
+var monotonicNow = func() (time.Duration, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return 0, err
+	}
+	return time.Duration(ts.Nano()), nil
+}
+
+// usecTime converts a realtime timestamp in usec to a time, the zero time
+// is returned if the timestamp isn't set
+func usecTime(usec uint64) time.Time {
+	if usec == 0 || usec == Infinity {
+		return time.Time{}
+	}
+	return time.UnixMicro(int64(usec))
+}
+
+// monotonicTime converts a monotonic timestamp in usec to the wall clock
+func monotonicTime(usec uint64, now time.Time, monoNow time.Duration) time.Time {
+	if usec == 0 || usec == Infinity {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(usec)*time.Microsecond - monoNow)
+}
+
+type ListTimersParams struct {
+	All bool `json:"all,omitempty" jsonschema:"Also list the inactive timers."`
+}
+
+// formatTime returns the time in RFC 3339 format or "" for the zero time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+type TimerInfo struct {
+	Timer           string `json:"timer"`
+	State           string `json:"state"`
+	Next            string `json:"next,omitempty"`
+	Left            string `json:"left,omitempty"`
+	NextRealtime    string `json:"next_realtime,omitempty"`
+	NextMonotonic   string `json:"next_monotonic,omitempty"`
+	Last            string `json:"last,omitempty"`
+	Passed          string `json:"passed,omitempty"`
+	Result          string `json:"result,omitempty"`
+	Unit            string `json:"activates"`
+	UnitActiveState string `json:"unit_active_state,omitempty"`
+	UnitResult      string `json:"unit_result,omitempty"`
+	next            time.Time
+}
+
+// timerInfo reads the elapse times of the timer and the result of the unit
+// it activates
+func (conn *Connection) timerInfo(ctx context.Context, unit dbus.UnitStatus, now time.Time, monoNow time.Duration) (TimerInfo, error) {
+	info := TimerInfo{Timer: unit.Name, State: unit.ActiveState}
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, unit.Name)
+	if err != nil {
+		return info, err
+	}
+	realtime, _ := props["NextElapseUSecRealtime"].(uint64)
+	monotonic, _ := props["NextElapseUSecMonotonic"].(uint64)
+	nextRealtime := usecTime(realtime)
+	nextMonotonic := monotonicTime(monotonic, now, monoNow)
+	// like systemctl the earlier of both elapse times is the next one
+	info.next = nextRealtime
+	if !nextMonotonic.IsZero() && (info.next.IsZero() || nextMonotonic.Before(info.next)) {
+		info.next = nextMonotonic
+	}
+	info.Next = formatTime(info.next)
+	info.NextRealtime = formatTime(nextRealtime)
+	info.NextMonotonic = formatTime(nextMonotonic)
+	if !info.next.IsZero() {
+		info.Left = info.next.Sub(now).Round(time.Second).String()
+	}
+	lastUSec, _ := props["LastTriggerUSec"].(uint64)
+	if last := usecTime(lastUSec); !last.IsZero() {
+		info.Last = formatTime(last)
+		info.Passed = now.Sub(last).Round(time.Second).String()
+	}
+	info.Result, _ = props["Result"].(string)
+	info.Unit, _ = props["Unit"].(string)
+	if info.Unit != "" {
+		unitProps, err := conn.dbus.GetAllPropertiesContext(ctx, info.Unit)
+		if err == nil {
+			info.UnitActiveState, _ = unitProps["ActiveState"].(string)
+			info.UnitResult, _ = unitProps["Result"].(string)
+		}
+	}
+	return info, nil
+}
+
+// list the timers sorted by their next elapse
+func (conn *Connection) ListTimers(ctx context.Context, req *mcp.CallToolRequest, params *ListTimersParams) (*mcp.CallToolResult, any, error) {
+	states := []string{"active"}
+	if params.All {
+		states = []string{}
+	}
+	units, err := conn.dbus.ListUnitsByPatternsContext(ctx, states, []string{"*.timer"})
+	if err != nil {
+		return nil, nil, err
+	}
+	monoNow, err := monotonicNow()
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't read monotonic clock: %w", err)
+	}
+	now := time.Now()
+	timers := []TimerInfo{}
+	for _, unit := range units {
+		info, err := conn.timerInfo(ctx, unit, now, monoNow)
+		if err != nil {
+			return nil, nil, err
+		}
+		timers = append(timers, info)
+	}
+	// timers without next elapse are listed at the end
+	slices.SortStableFunc(timers, func(a, b TimerInfo) int {
+		switch {
+		case a.next.IsZero() && b.next.IsZero():
+			return 0
+		case a.next.IsZero():
+			return 1
+		case b.next.IsZero():
+			return -1
+		}
+		return a.next.Compare(b.next)
+	})
+	txtContentList := []mcp.Content{}
+	for _, timer := range timers {
+		jsonByte, err := json.Marshal(timer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	if len(txtContentList) == 0 {
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: "no timers found",
+		})
+	}
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+	}, nil, nil
+}
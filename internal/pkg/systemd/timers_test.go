@@ -0,0 +1,73 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListTimers(t *testing.T) {
+	oldNow := monotonicNow
+	monotonicNow = func() (time.Duration, error) { return time.Hour, nil }
+	defer func() { monotonicNow = oldNow }()
+	now := time.Now()
+	var gotStates []string
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			listUnitsByPatterns: func(patterns []string, states []string) ([]dbus.UnitStatus, error) {
+				gotStates = states
+				return []dbus.UnitStatus{
+					{Name: "never.timer", ActiveState: "inactive"},
+					{Name: "daily.timer", ActiveState: "active"},
+					{Name: "boot.timer", ActiveState: "active"},
+				}, nil
+			},
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				switch unitName {
+				case "daily.timer":
+					return map[string]interface{}{
+						"NextElapseUSecRealtime":  uint64(now.Add(10 * time.Hour).UnixMicro()),
+						"NextElapseUSecMonotonic": uint64(0),
+						"LastTriggerUSec":         uint64(now.Add(-14 * time.Hour).UnixMicro()),
+						"Unit":                    "daily.service",
+						"Result":                  "success",
+					}, nil
+				case "boot.timer":
+					// elapses 30 minutes after now
+					return map[string]interface{}{
+						"NextElapseUSecRealtime":  uint64(0),
+						"NextElapseUSecMonotonic": uint64((90 * time.Minute).Microseconds()),
+						"Unit":                    "boot.service",
+					}, nil
+				case "daily.service":
+					return map[string]interface{}{"ActiveState": "failed", "Result": "exit-code"}, nil
+				}
+				return map[string]interface{}{"Unit": "never.service"}, nil
+			},
+		},
+	}
+	res, _, err := conn.ListTimers(context.Background(), nil, &ListTimersParams{All: true})
+	assert.NoError(t, err)
+	assert.Empty(t, gotStates)
+	timers := []TimerInfo{}
+	for _, content := range res.Content {
+		var timer TimerInfo
+		assert.NoError(t, json.Unmarshal([]byte(content.(*mcp.TextContent).Text), &timer))
+		timers = append(timers, timer)
+	}
+	assert.Len(t, timers, 3)
+	assert.Equal(t, "boot.timer", timers[0].Timer)
+	assert.Equal(t, "30m0s", timers[0].Left)
+	assert.Equal(t, "daily.timer", timers[1].Timer)
+	assert.Equal(t, "10h0m0s", timers[1].Left)
+	assert.Equal(t, "14h0m0s", timers[1].Passed)
+	assert.Equal(t, "daily.service", timers[1].Unit)
+	assert.Equal(t, "exit-code", timers[1].UnitResult)
+	assert.Equal(t, "never.timer", timers[2].Timer)
+	assert.Empty(t, timers[2].Next)
+}
@@ -0,0 +1,415 @@
+package systemd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
+)
+
+// timerSpec is a monotonic timer of a transient timer
+type timerSpec struct {
+	Base string
+	USec uint64
+}
+
+// calendarSpec is a calendar timer of a transient timer
+type calendarSpec struct {
+	Base string
+	Spec string
+}
+
+type TransientParams struct {
+	Command          []string          `json:"command" jsonschema:"Command to run, the first element is the executable which must be allowed by the policy. It's looked up in PATH if it isn't an absolute path."`
+	Name             string            `json:"name,omitempty" jsonschema:"Name of the unit without suffix, a name like run-mcp-<random> is used if not set."`
+	Scope            bool              `json:"scope,omitempty" jsonschema:"Run the command as child of the server in a transient .scope instead of a .service. User and timers are not supported for scopes."`
+	Description      string            `json:"description,omitempty" jsonschema:"Description of the unit, defaults to the command line."`
+	User             string            `json:"user,omitempty" jsonschema:"User to run the command as, which must be allowed by the policy."`
+	WorkingDirectory string            `json:"working_directory,omitempty" jsonschema:"Working directory of the command."`
+	Environment      []string          `json:"environment,omitempty" jsonschema:"Environment variables in the form VAR=value. Variables which can run other code like LD_PRELOAD or PATH are refused."`
+	Properties       map[string]string `json:"properties,omitempty" jsonschema:"Resource limits like MemoryMax=512M or CPUQuota=50% and RuntimeMaxSec to stop the command after the given time span."`
+	OnActive         string            `json:"on_active,omitempty" jsonschema:"Create a transient timer which starts the command after this time span, like 5min."`
+	OnCalendar       string            `json:"on_calendar,omitempty" jsonschema:"Create a transient timer which starts the command at the calendar event, like 'daily' or '*-*-* 02:00:00'."`
+	Wait             bool              `json:"wait,omitempty" jsonschema:"Wait until the command exits and return its exit status and output. Not possible with timers."`
+	TimeOut          uint              `json:"timeout,omitempty" jsonschema:"Seconds to wait for the command with wait, defaults to 60. Without wait the time to wait for the start job, defaults to 3."`
+	Lines            int               `json:"lines,omitempty" jsonschema:"Number of output lines to return with wait, defaults to 20."`
+}
+
+type TransientResult struct {
+	Unit       string   `json:"unit"`
+	Timer      string   `json:"timer,omitempty"`
+	Job        string   `json:"job,omitempty"`
+	JobResult  string   `json:"job_result,omitempty"`
+	State      string   `json:"state,omitempty"`
+	ExitCode   string   `json:"exit_code,omitempty"`
+	ExitStatus string   `json:"exit_status,omitempty"`
+	Output     []string `json:"output,omitempty"`
+	Notes      []string `json:"notes,omitempty"`
+}
+
+// transientName returns the name of the unit with the suffix of its type
+func transientName(name, suffix string) (string, error) {
+	if name == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return "", err
+		}
+		name = "run-mcp-" + hex.EncodeToString(id)
+	}
+	if KnownSections(UnitType(name)) != nil {
+		name = strings.TrimSuffix(name, "."+UnitType(name))
+	}
+	name += "." + suffix
+	if err := checkUnitName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// resolveCommand looks up the executable of the command and checks it
+// against the policy
+func (conn *Connection) resolveCommand(command []string) ([]string, error) {
+	if len(command) == 0 || command[0] == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	path := command[0]
+	if !filepath.IsAbs(path) {
+		var err error
+		if path, err = exec.LookPath(path); err != nil {
+			return nil, err
+		}
+		if path, err = filepath.Abs(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.policy.CheckExecutable(path); err != nil {
+		return nil, err
+	}
+	return append([]string{filepath.Clean(path)}, command[1:]...), nil
+}
+
+// transientProperties returns the properties which are shared by services
+// and scopes
+func transientProperties(params *TransientParams, command []string) ([]dbus.Property, error) {
+	description := params.Description
+	if description == "" {
+		description = strings.Join(command, " ")
+	}
+	props := []dbus.Property{dbus.PropDescription(description)}
+	for _, name := range slices.Sorted(maps.Keys(params.Properties)) {
+		prop, err := resourcePropertyValue(name, params.Properties[name])
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, prop)
+	}
+	return props, nil
+}
+
+// run a command as transient service or scope
+func (conn *Connection) RunTransient(ctx context.Context, req *mcp.CallToolRequest, params *TransientParams) (*mcp.CallToolResult, any, error) {
+	if params.TimeOut > MaxTimeOut {
+		return nil, nil, fmt.Errorf("not waiting longer than MaxTimeOut(%d), longer operation will run in the background and result can be gathered with separate function.", MaxTimeOut)
+	}
+	command, err := conn.resolveCommand(params.Command)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := policy.CheckEnvironment(params.Environment); err != nil {
+		return nil, nil, err
+	}
+	if params.User != "" {
+		if err := conn.policy.CheckUser(params.User); err != nil {
+			return nil, nil, err
+		}
+	}
+	hasTimer := params.OnActive != "" || params.OnCalendar != ""
+	if hasTimer && params.Wait {
+		return nil, nil, fmt.Errorf("wait isn't possible for commands started by a timer")
+	}
+	props, err := transientProperties(params, command)
+	if err != nil {
+		return nil, nil, err
+	}
+	var result *TransientResult
+	if params.Scope {
+		if hasTimer || params.User != "" {
+			return nil, nil, fmt.Errorf("user and timers are not supported for scopes")
+		}
+		result, err = conn.runScope(ctx, params, command, props)
+	} else {
+		result, err = conn.runService(ctx, params, command, props)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+		IsError: result.JobResult != "" && result.JobResult != "done",
+	}, nil, nil
+}
+
+// runService starts the command as transient service, optionally triggered
+// by a transient timer
+func (conn *Connection) runService(ctx context.Context, params *TransientParams, command []string, props []dbus.Property) (*TransientResult, error) {
+	name, err := transientName(params.Name, "service")
+	if err != nil {
+		return nil, err
+	}
+	props = append(props, dbus.PropExecStart(command, true), dbus.PropType("exec"))
+	if params.User != "" {
+		props = append(props, dbus.Property{Name: "User", Value: godbus.MakeVariant(params.User)})
+	}
+	if params.WorkingDirectory != "" {
+		props = append(props, dbus.Property{Name: "WorkingDirectory", Value: godbus.MakeVariant(params.WorkingDirectory)})
+	}
+	if len(params.Environment) > 0 {
+		props = append(props, dbus.Property{Name: "Environment", Value: godbus.MakeVariant(params.Environment)})
+	}
+	result := &TransientResult{Unit: name}
+	if params.OnActive != "" || params.OnCalendar != "" {
+		return conn.runTimer(ctx, params, result, props)
+	}
+	if params.Wait {
+		// keep the unit after the command exited, so that its exit status
+		// can be read
+		props = append(props, dbus.PropRemainAfterExit(true))
+	}
+	jobChan := make(chan string, 1)
+	if _, err := conn.dbus.StartTransientUnitContext(ctx, name, "fail", props, jobChan); err != nil {
+		return nil, fmt.Errorf("couldn't start %s: %w", name, err)
+	}
+	if !params.Wait {
+		result.JobResult = waitJob(jobChan, params.TimeOut)
+		if result.JobResult == "" {
+			result.Notes = append(result.Notes, "start job still in progress")
+		}
+		return result, nil
+	}
+	timeOut := params.TimeOut
+	if timeOut == 0 {
+		timeOut = 60
+	}
+	deadline := time.Now().Add(time.Duration(timeOut) * time.Second)
+	result.JobResult = waitJob(jobChan, timeOut)
+	if result.JobResult != "done" {
+		if result.JobResult == "" {
+			result.Notes = append(result.Notes, "start job still in progress")
+		}
+		return result, nil
+	}
+	for {
+		unitProps, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		active, _ := unitProps["ActiveState"].(string)
+		sub, _ := unitProps["SubState"].(string)
+		if active == "failed" || active == "inactive" || sub == "exited" {
+			result.State = active
+			code, _ := unitProps["ExecMainCode"].(int32)
+			status, _ := unitProps["ExecMainStatus"].(int32)
+			result.ExitCode = ExitCodeName(code)
+			result.ExitStatus = DecodeExitStatus(code, status)
+			break
+		}
+		if time.Now().After(deadline) {
+			result.State = active
+			result.Notes = append(result.Notes, fmt.Sprintf("command still running after %ds, check it with list_log or stop it with stop_unit", timeOut))
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	result.Output = conn.transientOutput(ctx, name, params.Lines, result)
+	// remove the unit, as it was kept for reading the exit status
+	if result.State == "failed" {
+		err = conn.dbus.ResetFailedUnitContext(ctx, name)
+	} else {
+		_, err = conn.dbus.StopUnitContext(ctx, name, "replace", nil)
+	}
+	if err != nil {
+		result.Notes = append(result.Notes, fmt.Sprintf("couldn't remove %s: %s", name, err))
+	}
+	return result, nil
+}
+
+// transientOutput returns the last lines the command wrote to the journal
+func (conn *Connection) transientOutput(ctx context.Context, name string, lines int, result *TransientResult) []string {
+	if lines <= 0 {
+		lines = 20
+	}
+	if conn.unitLog == nil {
+		result.Notes = append(result.Notes, "journal not available, no output included")
+		return nil
+	}
+	output, err := conn.unitLog.InvocationOutput(ctx, name, "latest", lines)
+	if err != nil {
+		result.Notes = append(result.Notes, fmt.Sprintf("couldn't get output: %s", err))
+	}
+	return output
+}
+
+// runTimer creates a transient timer with the transient service
+func (conn *Connection) runTimer(ctx context.Context, params *TransientParams, result *TransientResult, serviceProps []dbus.Property) (*TransientResult, error) {
+	result.Timer = strings.TrimSuffix(result.Unit, ".service") + ".timer"
+	timerProps := []dbus.Property{
+		dbus.PropDescription(fmt.Sprintf("Timer for %s", result.Unit)),
+		{Name: "RemainAfterElapse", Value: godbus.MakeVariant(false)},
+	}
+	if params.OnActive != "" {
+		usec, err := ParseTimeSpan(params.OnActive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for on_active: %w", err)
+		}
+		timerProps = append(timerProps, dbus.Property{Name: "TimersMonotonic", Value: godbus.MakeVariant([]timerSpec{{Base: "OnActiveUSec", USec: usec}})})
+	}
+	if params.OnCalendar != "" {
+		timerProps = append(timerProps, dbus.Property{Name: "TimersCalendar", Value: godbus.MakeVariant([]calendarSpec{{Base: "OnCalendar", Spec: params.OnCalendar}})})
+	}
+	job, err := conn.dbus.StartTransientUnitAuxContext(ctx, result.Timer, "fail", timerProps, []AuxUnit{{Name: result.Unit, Properties: serviceProps}})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't start %s: %w", result.Timer, err)
+	}
+	result.Job = job
+	result.Notes = append(result.Notes, "the command runs when the timer elapses, check the timer with list_units")
+	return result, nil
+}
+
+// maxScopeOutput is the number of bytes of the output of a scope which are
+// kept, the output of a service is read from the journal instead
+const maxScopeOutput = 64 << 10
+
+// tailBuffer keeps the last bytes written to it, so that a command can't
+// make the server buffer unlimited output
+type tailBuffer struct {
+	mu        sync.Mutex
+	buf       []byte
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > maxScopeOutput {
+		b.buf = slices.Clone(b.buf[len(b.buf)-maxScopeOutput:])
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// lines returns the complete lines in the buffer, the first line is
+// dropped if the start of it was discarded
+func (b *tailBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	text := string(b.buf)
+	if b.truncated {
+		_, text, _ = strings.Cut(text, "\n")
+	}
+	if text = strings.TrimRight(text, "\n"); text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// scopePath is the PATH of commands in a scope, the default of the manager
+const scopePath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin"
+
+// runScope starts the command as child of the server and moves it into a
+// transient scope
+func (conn *Connection) runScope(ctx context.Context, params *TransientParams, command []string, props []dbus.Property) (*TransientResult, error) {
+	name, err := transientName(params.Name, "scope")
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = params.WorkingDirectory
+	// don't pass the environment of the server, a service gets only PATH
+	// from the manager as well
+	cmd.Env = append([]string{"PATH=" + scopePath}, params.Environment...)
+	output := &tailBuffer{}
+	if params.Wait {
+		cmd.Stdout = output
+		cmd.Stderr = output
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	props = append(props, dbus.PropPids(uint32(cmd.Process.Pid)))
+	jobChan := make(chan string, 1)
+	if _, err := conn.dbus.StartTransientUnitContext(ctx, name, "fail", props, jobChan); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("couldn't start %s: %w", name, err)
+	}
+	result := &TransientResult{Unit: name}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	result.JobResult = waitJob(jobChan, 0)
+	if !params.Wait {
+		return result, nil
+	}
+	timeOut := params.TimeOut
+	if timeOut == 0 {
+		timeOut = 60
+	}
+	select {
+	case err := <-done:
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return nil, err
+		}
+		// use the codes of the manager, so that the result looks like the
+		// one of a service
+		code, status := int32(1), int32(cmd.ProcessState.ExitCode())
+		if ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			code, status = 2, int32(ws.Signal())
+		}
+		result.State = "exited"
+		result.ExitCode = ExitCodeName(code)
+		result.ExitStatus = DecodeExitStatus(code, status)
+	case <-time.After(time.Duration(timeOut) * time.Second):
+		result.State = "running"
+		result.Notes = append(result.Notes, fmt.Sprintf("command still running after %ds, stop it with stop_unit", timeOut))
+		return result, nil
+	}
+	lines := params.Lines
+	if lines <= 0 {
+		lines = 20
+	}
+	result.Output = output.lines()
+	if len(result.Output) > lines {
+		result.Output = result.Output[len(result.Output)-lines:]
+	}
+	return result, nil
+}
@@ -0,0 +1,133 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunTransientWait(t *testing.T) {
+	var gotProps []dbus.Property
+	stopped := ""
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			startTransientUnit: func(name string, mode string, properties []dbus.Property, ch chan<- string) (int, error) {
+				gotProps = properties
+				ch <- "done"
+				return 1, nil
+			},
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"ActiveState":    "active",
+					"SubState":       "exited",
+					"ExecMainCode":   int32(1),
+					"ExecMainStatus": int32(0),
+				}, nil
+			},
+			stopUnit: func(name string, mode string, ch chan<- string) (int, error) {
+				stopped = name
+				return 1, nil
+			},
+		},
+		unitLog: &mockUnitLog{output: []string{"hello"}},
+		policy:  &policy.Policy{AllowedExecutables: []string{"/usr/bin/*"}, AllowedUsers: []string{"nobody"}},
+	}
+	res, _, err := conn.RunTransient(context.Background(), nil, &TransientParams{
+		Command:    []string{"/usr/bin/echo", "hello"},
+		Name:       "hello",
+		User:       "nobody",
+		Properties: map[string]string{"MemoryMax": "64M", "RuntimeMaxSec": "1min"},
+		Wait:       true,
+	})
+	assert.NoError(t, err)
+	var result TransientResult
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Equal(t, TransientResult{
+		Unit:       "hello.service",
+		JobResult:  "done",
+		State:      "active",
+		ExitCode:   "exited",
+		ExitStatus: "SUCCESS",
+		Output:     []string{"hello"},
+	}, result)
+	assert.Equal(t, "hello.service", stopped)
+	names := []string{}
+	for _, prop := range gotProps {
+		names = append(names, prop.Name)
+	}
+	assert.Equal(t, []string{"Description", "MemoryMax", "RuntimeMaxUSec", "ExecStart", "Type", "User", "RemainAfterExit"}, names)
+}
+
+func TestRunTransientChecks(t *testing.T) {
+	conn := &Connection{
+		dbus:   &mockDbusConnection{},
+		policy: &policy.Policy{AllowedExecutables: []string{"/usr/bin/*"}},
+	}
+	_, _, err := conn.RunTransient(context.Background(), nil, &TransientParams{
+		Command: []string{"/usr/bin/echo"},
+		TimeOut: MaxTimeOut + 1,
+	})
+	assert.ErrorContains(t, err, "MaxTimeOut")
+	for _, env := range []string{"LD_PRELOAD=/tmp/x.so", "PATH=/tmp"} {
+		_, _, err = conn.RunTransient(context.Background(), nil, &TransientParams{
+			Command:     []string{"/usr/bin/echo"},
+			Environment: []string{"FOO=bar", env},
+		})
+		assert.ErrorContains(t, err, "not allowed", env)
+	}
+	_, _, err = conn.RunTransient(context.Background(), nil, &TransientParams{
+		Command: []string{"/usr/bin/echo"},
+		User:    "root",
+	})
+	assert.ErrorContains(t, err, "-allow-user")
+}
+
+func TestTailBuffer(t *testing.T) {
+	output := &tailBuffer{}
+	output.Write([]byte("first\nsecond\n"))
+	assert.Equal(t, []string{"first", "second"}, output.lines())
+	for i := 0; i < maxScopeOutput/8; i++ {
+		output.Write([]byte("1234567\n"))
+	}
+	output.Write([]byte("last\n"))
+	lines := output.lines()
+	assert.Len(t, output.buf, maxScopeOutput)
+	assert.Equal(t, "1234567", lines[0])
+	assert.Equal(t, "last", lines[len(lines)-1])
+	assert.Len(t, lines, maxScopeOutput/8)
+}
+
+func TestRunTransientTimer(t *testing.T) {
+	var gotAux []AuxUnit
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			startTransientAux: func(name string, mode string, properties []dbus.Property, aux []AuxUnit) (string, error) {
+				assert.Equal(t, "backup.timer", name)
+				gotAux = aux
+				return "/org/freedesktop/systemd1/job/1", nil
+			},
+		},
+		policy: &policy.Policy{AllowedExecutables: []string{"/usr/bin/*"}},
+	}
+	res, _, err := conn.RunTransient(context.Background(), nil, &TransientParams{
+		Command:  []string{"/usr/bin/true"},
+		Name:     "backup.service",
+		OnActive: "5min",
+	})
+	assert.NoError(t, err)
+	var result TransientResult
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Equal(t, "backup.timer", result.Timer)
+	assert.Len(t, gotAux, 1)
+	assert.Equal(t, "backup.service", gotAux[0].Name)
+
+	_, _, err = conn.RunTransient(context.Background(), nil, &TransientParams{Command: []string{"/usr/sbin/reboot"}})
+	assert.ErrorContains(t, err, "not allowed")
+	_, _, err = conn.RunTransient(context.Background(), nil, &TransientParams{Command: []string{"/usr/bin/true"}, OnActive: "5min", Wait: true})
+	assert.Error(t, err)
+}
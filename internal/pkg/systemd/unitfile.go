@@ -0,0 +1,131 @@
+package systemd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UnitOption is a single assignment in a unit file
+type UnitOption struct {
+	Section string `json:"section"`
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+}
+
+// UnitFileError is a syntax error in a unit file, which doesn't stop the
+// parsing, as systemd also ignores these lines
+type UnitFileError struct {
+	File string `json:"file,omitempty"`
+	Line int    `json:"line"`
+	Msg  string `json:"message"`
+}
+
+func (e UnitFileError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// ParseUnitFile parses a unit file with the syntax described in
+// systemd.syntax(7). Comments start with '#' or ';', a trailing backslash
+// continues the line and the assignments are stored with their section and
+// the line they start in.
+func ParseUnitFile(r io.Reader, file string) (opts []UnitOption, errs []UnitFileError, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	section := ""
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		startLine := lineNr
+		// join continued lines, comments in between are ignored
+		for strings.HasSuffix(line, "\\") && !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, ";") {
+			line = strings.TrimSuffix(line, "\\") + " "
+			if !scanner.Scan() {
+				break
+			}
+			lineNr++
+			next := strings.TrimSpace(scanner.Text())
+			for next != "" && (next[0] == '#' || next[0] == ';') {
+				if !scanner.Scan() {
+					next = ""
+					break
+				}
+				lineNr++
+				next = strings.TrimSpace(scanner.Text())
+			}
+			line += next
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' || len(line) < 3 {
+				errs = append(errs, UnitFileError{File: file, Line: startLine, Msg: fmt.Sprintf("invalid section header: %s", line)})
+				section = ""
+				continue
+			}
+			section = line[1 : len(line)-1]
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			errs = append(errs, UnitFileError{File: file, Line: startLine, Msg: fmt.Sprintf("missing '=', ignoring line: %s", line)})
+			continue
+		}
+		if section == "" {
+			errs = append(errs, UnitFileError{File: file, Line: startLine, Msg: fmt.Sprintf("assignment outside of section: %s", key)})
+			continue
+		}
+		opts = append(opts, UnitOption{
+			Section: section,
+			Name:    key,
+			Value:   strings.TrimSpace(value),
+			File:    file,
+			Line:    startLine,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return opts, errs, nil
+}
+
+// MergeUnitOptions merges the options of the fragment and its drop-ins in
+// the given order. As in systemd an empty assignment resets the values
+// assigned before. All remaining values of a setting are returned in
+// order, none is picked for settings which only take a single value, for
+// them systemd uses the last one.
+func MergeUnitOptions(opts []UnitOption) map[string]map[string][]string {
+	merged := make(map[string]map[string][]string)
+	for _, opt := range opts {
+		if _, ok := merged[opt.Section]; !ok {
+			merged[opt.Section] = make(map[string][]string)
+		}
+		if opt.Value == "" {
+			merged[opt.Section][opt.Name] = []string{}
+			continue
+		}
+		merged[opt.Section][opt.Name] = append(merged[opt.Section][opt.Name], opt.Value)
+	}
+	// settings which were only reset aren't effective
+	for section, settings := range merged {
+		for key, values := range settings {
+			if len(values) == 0 {
+				delete(settings, key)
+			}
+		}
+		if len(settings) == 0 {
+			delete(merged, section)
+		}
+	}
+	return merged
+}
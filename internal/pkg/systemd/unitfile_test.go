@@ -0,0 +1,102 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUnitFile(t *testing.T) {
+	content := `# comment
+[Unit]
+Description=Foo \
+  service
+; another comment
+After=network.target
+
+[Service]
+ExecStart=/usr/bin/foo
+Environment=A=1 B=2
+broken line
+[Install
+WantedBy=multi-user.target
+`
+	opts, errs, err := ParseUnitFile(strings.NewReader(content), "foo.service")
+	assert.NoError(t, err)
+	assert.Equal(t, []UnitOption{
+		{Section: "Unit", Name: "Description", Value: "Foo  service", File: "foo.service", Line: 3},
+		{Section: "Unit", Name: "After", Value: "network.target", File: "foo.service", Line: 6},
+		{Section: "Service", Name: "ExecStart", Value: "/usr/bin/foo", File: "foo.service", Line: 9},
+		{Section: "Service", Name: "Environment", Value: "A=1 B=2", File: "foo.service", Line: 10},
+	}, opts)
+	assert.Len(t, errs, 3)
+	assert.Equal(t, 11, errs[0].Line)
+	assert.Equal(t, 12, errs[1].Line)
+	assert.Equal(t, "foo.service:13: assignment outside of section: WantedBy", errs[2].Error())
+}
+
+func TestMergeUnitOptions(t *testing.T) {
+	merged := MergeUnitOptions([]UnitOption{
+		{Section: "Service", Name: "ExecStart", Value: "/usr/bin/foo"},
+		{Section: "Service", Name: "Environment", Value: "A=1"},
+		{Section: "Service", Name: "ExecStart", Value: ""},
+		{Section: "Service", Name: "ExecStart", Value: "/usr/bin/bar"},
+		{Section: "Service", Name: "Environment", Value: "B=2"},
+		{Section: "Unit", Name: "After", Value: ""},
+	})
+	assert.Equal(t, map[string]map[string][]string{
+		"Service": {
+			"ExecStart":   {"/usr/bin/bar"},
+			"Environment": {"A=1", "B=2"},
+		},
+	}, merged)
+}
+
+func TestCatUnit(t *testing.T) {
+	dir := t.TempDir()
+	oldPaths := unitSearchPaths
+	unitSearchPaths = []string{dir}
+	defer func() { unitSearchPaths = oldPaths }()
+	fragment := filepath.Join(dir, "foo.service")
+	dropIn := filepath.Join(dir, "foo.service.d", "override.conf")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(dropIn), 0o755))
+	assert.NoError(t, os.WriteFile(fragment, []byte("[Service]\nExecStart=/usr/bin/foo\nRestart=no\n"), 0o644))
+	assert.NoError(t, os.WriteFile(dropIn, []byte("[Service]\nRestart=always\n"), 0o644))
+	secret := filepath.Join(t.TempDir(), "secret")
+	assert.NoError(t, os.WriteFile(secret, []byte("[Service]\nPassword=x\n"), 0o600))
+	link := filepath.Join(dir, "foo.service.d", "link.conf")
+	assert.NoError(t, os.Symlink(secret, link))
+	inside := filepath.Join(dir, "foo.service.d", "inside.conf")
+	assert.NoError(t, os.Symlink(dropIn, inside))
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"FragmentPath": fragment,
+					"DropInPaths":  []string{dropIn, "/tmp/../etc/shadow", link, inside},
+				}, nil
+			},
+		},
+	}
+	res, _, err := conn.CatUnit(context.Background(), nil, &CatUnitParams{Name: "foo.service"})
+	assert.NoError(t, err)
+	var result CatUnitResult
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+	assert.Len(t, result.Files, 5)
+	assert.Equal(t, fragment, result.Files[0].Path)
+	assert.Equal(t, dropIn, result.Files[1].Path)
+	assert.Contains(t, result.Files[2].Error, "not in the unit search paths")
+	assert.Empty(t, result.Files[2].Content)
+	assert.Contains(t, result.Files[3].Error, "which is not in the unit search paths")
+	assert.Empty(t, result.Files[3].Content)
+	assert.Equal(t, "[Service]\nRestart=always\n", result.Files[4].Content)
+	assert.NotContains(t, result.Settings["Service"], "Password")
+	assert.Equal(t, []string{"/usr/bin/foo"}, result.Settings["Service"]["ExecStart"])
+	assert.Equal(t, []string{"no", "always", "always"}, result.Settings["Service"]["Restart"])
+}
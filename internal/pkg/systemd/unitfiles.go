@@ -0,0 +1,136 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// UnitFileChange is a symlink which was created or removed by an unit file
+// operation
+type UnitFileChange struct {
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Destination string `json:"destination"`
+}
+
+// unitFileChangesResult returns the changes as json or a message that
+// nothing was changed
+func unitFileChangesResult(files []string, changes []UnitFileChange) (*mcp.CallToolResult, any, error) {
+	if len(changes) == 0 {
+		names := strings.Join(files, ", ")
+		if names == "" {
+			names = "all units"
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: fmt.Sprintf("nothing changed for %s", names),
+				},
+			},
+		}, nil, nil
+	}
+	txtContentList := []mcp.Content{}
+	for _, change := range changes {
+		jsonByte, err := json.Marshal(change)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+	}, nil, nil
+}
+
+// toUnitFileChanges converts the change types of go-systemd, which all have
+// the same layout
+func toUnitFileChanges[T dbus.EnableUnitFileChange | dbus.DisableUnitFileChange | dbus.MaskUnitFileChange | dbus.UnmaskUnitFileChange | dbus.LinkUnitFileChange](in []T) []UnitFileChange {
+	changes := make([]UnitFileChange, 0, len(in))
+	for _, change := range in {
+		changes = append(changes, UnitFileChange(dbus.EnableUnitFileChange(change)))
+	}
+	return changes
+}
+
+// ValidUnitFileActions returns the actions of ManageUnitFile
+func ValidUnitFileActions() []string {
+	return []string{"mask", "unmask", "link", "preset", "preset-all"}
+}
+
+// ValidPresetModes returns the modes for applying the preset policy
+func ValidPresetModes() []string {
+	return []string{"full", "enable-only", "disable-only"}
+}
+
+type UnitFileParams struct {
+	Action     string   `json:"action" jsonschema:"Action to perform, one of mask, unmask, link, preset or preset-all. 'mask' links the unit to /dev/null so that it can't be started at all, 'unmask' reverts this. 'link' makes a unit file outside of the search paths available. 'preset' enables or disables the units according to the preset policy, 'preset-all' does this for all units."`
+	Files      []string `json:"files,omitempty" jsonschema:"Names of the units, for 'link' the absolute paths of the unit files. Not used for 'preset-all'."`
+	Runtime    bool     `json:"runtime,omitempty" jsonschema:"Only make the change in /run so that it's gone after the next reboot, instead of the persistent change in /etc."`
+	Force      bool     `json:"force,omitempty" jsonschema:"Replace existing symlinks which conflict with the change."`
+	PresetMode string   `json:"preset_mode,omitempty" jsonschema:"Mode for preset and preset-all, one of full (default), enable-only or disable-only."`
+}
+
+// mask, unmask, link or preset unit files
+func (conn *Connection) ManageUnitFile(ctx context.Context, req *mcp.CallToolRequest, params *UnitFileParams) (res *mcp.CallToolResult, _ any, err error) {
+	if !slices.Contains(ValidUnitFileActions(), params.Action) {
+		return nil, nil, fmt.Errorf("invalid action %s, valid actions are: %v", params.Action, ValidUnitFileActions())
+	}
+	if params.Action != "preset-all" && len(params.Files) == 0 {
+		return nil, nil, fmt.Errorf("no files given for %s", params.Action)
+	}
+	mode := params.PresetMode
+	if mode == "" {
+		mode = "full"
+	}
+	if !slices.Contains(ValidPresetModes(), mode) {
+		return nil, nil, fmt.Errorf("invalid preset mode %s, valid modes are: %v", mode, ValidPresetModes())
+	}
+	var changes []UnitFileChange
+	switch params.Action {
+	case "mask":
+		maskRes, err := conn.dbus.MaskUnitFilesContext(ctx, params.Files, params.Runtime, params.Force)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error when masking: %w", err)
+		}
+		changes = toUnitFileChanges(maskRes)
+	case "unmask":
+		unmaskRes, err := conn.dbus.UnmaskUnitFilesContext(ctx, params.Files, params.Runtime)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error when unmasking: %w", err)
+		}
+		changes = toUnitFileChanges(unmaskRes)
+	case "link":
+		for _, file := range params.Files {
+			if !filepath.IsAbs(file) {
+				return nil, nil, fmt.Errorf("link needs the absolute path of the unit file, got: %s", file)
+			}
+		}
+		linkRes, err := conn.dbus.LinkUnitFilesContext(ctx, params.Files, params.Runtime, params.Force)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error when linking: %w", err)
+		}
+		changes = toUnitFileChanges(linkRes)
+	case "preset":
+		_, presetRes, err := conn.dbus.PresetUnitFilesWithModeContext(ctx, params.Files, mode, params.Runtime, params.Force)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error when applying preset: %w", err)
+		}
+		changes = toUnitFileChanges(presetRes)
+	case "preset-all":
+		presetRes, err := conn.dbus.PresetAllUnitFilesContext(ctx, mode, params.Runtime, params.Force)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error when applying preset to all units: %w", err)
+		}
+		changes = toUnitFileChanges(presetRes)
+	}
+	return unitFileChangesResult(params.Files, changes)
+}
@@ -0,0 +1,39 @@
+package systemd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManageUnitFile(t *testing.T) {
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			maskUnitFiles: func(files []string, runtime bool, force bool) ([]dbus.MaskUnitFileChange, error) {
+				assert.True(t, runtime)
+				return []dbus.MaskUnitFileChange{{Type: "symlink", Filename: "/run/systemd/system/foo.service", Destination: "/dev/null"}}, nil
+			},
+			presetAllUnitFiles: func(mode string, runtime bool, force bool) ([]dbus.EnableUnitFileChange, error) {
+				assert.Equal(t, "enable-only", mode)
+				return nil, nil
+			},
+		},
+	}
+	res, _, err := conn.ManageUnitFile(context.Background(), nil, &UnitFileParams{Action: "mask", Files: []string{"foo.service"}, Runtime: true})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"symlink","filename":"/run/systemd/system/foo.service","destination":"/dev/null"}`, res.Content[0].(*mcp.TextContent).Text)
+
+	res, _, err = conn.ManageUnitFile(context.Background(), nil, &UnitFileParams{Action: "preset-all", PresetMode: "enable-only"})
+	assert.NoError(t, err)
+	assert.Equal(t, "nothing changed for all units", res.Content[0].(*mcp.TextContent).Text)
+
+	_, _, err = conn.ManageUnitFile(context.Background(), nil, &UnitFileParams{Action: "link", Files: []string{"foo.service"}})
+	assert.Error(t, err)
+	_, _, err = conn.ManageUnitFile(context.Background(), nil, &UnitFileParams{Action: "enable", Files: []string{"foo.service"}})
+	assert.Error(t, err)
+	_, _, err = conn.ManageUnitFile(context.Background(), nil, &UnitFileParams{Action: "preset", Files: []string{"foo.service"}, PresetMode: "bar"})
+	assert.Error(t, err)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"path"
 	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-systemd/v22/dbus"
@@ -164,6 +166,7 @@ func (conn *Connection) ListUnitHandlerNameState(ctx context.Context, req *mcp.C
 				// Active state info
 				ActiveState          string `json:"ActiveState"`
 				SubState             string `json:"SubState"`
+				FreezerState         string `json:"FreezerState"`
 				ActiveEnterTimestamp uint64 `json:"ActiveEnterTimestamp"`
 
 				// Process info
@@ -283,17 +286,15 @@ func (conn *Connection) RestartReloadUnit(ctx context.Context, req *mcp.CallTool
 	if params.TimeOut > MaxTimeOut {
 		return nil, nil, fmt.Errorf("not waiting longer than MaxTimeOut(%d), longer operation will run in the background and result can be gathered with separate function.", MaxTimeOut)
 	}
+	job := conn.dbus.ReloadOrRestartUnitContext
 	if params.Forcerestart {
-		_, err = conn.dbus.RestartUnitContext(ctx, params.Name, params.Mode, conn.rchannel)
-	} else {
-		_, err = conn.dbus.ReloadOrRestartUnitContext(ctx, params.Name, params.Mode, conn.rchannel)
+		job = conn.dbus.RestartUnitContext
 	}
+	result, err := conn.runJob(ctx, job, params.Name, params.Mode, params.TimeOut)
 	if err != nil {
 		return nil, nil, err
 	}
-	return conn.CheckForRestartReloadRunning(ctx, req, &RestartReloadParams{
-		TimeOut: params.TimeOut,
-	})
+	return jobResult(result, "Reload or restart still in progress."), nil, nil
 }
 
 func (conn *Connection) StartUnit(ctx context.Context, req *mcp.CallToolRequest, params *RestartReloadParams) (res *mcp.CallToolResult, _ any, err error) {
@@ -306,46 +307,185 @@ func (conn *Connection) StartUnit(ctx context.Context, req *mcp.CallToolRequest,
 	if params.TimeOut > MaxTimeOut {
 		return nil, nil, fmt.Errorf("not waiting longer than MaxTimeOut(%d), longer operation will run in the background and result can be gathered with separate function.", MaxTimeOut)
 	}
-	_, err = conn.dbus.StartUnitContext(ctx, params.Name, params.Mode, conn.rchannel)
+	result, err := conn.runJob(ctx, conn.dbus.StartUnitContext, params.Name, params.Mode, params.TimeOut)
 	if err != nil {
+		if limitErr := conn.checkStartLimit(ctx, params.Name); limitErr != nil {
+			return nil, nil, limitErr
+		}
 		return nil, nil, err
 	}
-	return conn.CheckForRestartReloadRunning(ctx, req, &RestartReloadParams{
-		TimeOut: params.TimeOut,
-	})
-}
-
-type CheckReloadRestartParams struct {
-	TimeOut uint `json:"timeout" jsonschema:"Time to wait for the restart or reload to finish. After the timeout the function will return and restart and reload will run in the background and the result can be retreived with a separate function."`
-}
-
-// check status of reload or restart
-func (conn *Connection) CheckForRestartReloadRunning(ctx context.Context, req *mcp.CallToolRequest, params *RestartReloadParams) (res *mcp.CallToolResult, _ any, err error) {
-	select {
-	case result := <-conn.rchannel:
+	switch result {
+	case "":
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
-					Text: result,
+					Text: "Start still in progress.",
 				},
 			},
 		}, nil, nil
-	case <-time.After(3 * time.Second):
+	case "done":
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{
-					Text: "Reload or restart still in progress.",
+					Text: fmt.Sprintf("Started %s", params.Name),
 				},
 			},
 		}, nil, nil
-	default:
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Text: "Finished",
-				},
+	}
+	if limitErr := conn.checkStartLimit(ctx, params.Name); limitErr != nil {
+		return nil, nil, limitErr
+	}
+	return nil, nil, fmt.Errorf("start of %s finished with result '%s', use diagnose_unit to get the reason", params.Name, result)
+}
+
+// runJob runs the job of the unit and waits up to timeOut seconds for its
+// result, which is empty if the job is still running. An own channel is
+// used for every job, so that concurrent jobs don't get each others
+// result. A job which is still running is tracked, so that
+// check_restart_reload can return its result.
+func (conn *Connection) runJob(ctx context.Context, job jobFunc, name string, mode string, timeOut uint) (string, error) {
+	jobChan := make(chan string, 1)
+	if _, err := job(ctx, name, mode, jobChan); err != nil {
+		return "", err
+	}
+	result := waitJob(jobChan, timeOut)
+	if result == "" {
+		conn.jobs.add(name, jobChan)
+	}
+	return result, nil
+}
+
+// maxPendingJobs is the number of jobs which outlived their tool that are
+// tracked, the oldest one is dropped when another one is added
+const maxPendingJobs = 32
+
+// jobPollInterval is the interval in which the pending jobs are checked
+// while waiting for their results
+var jobPollInterval = 100 * time.Millisecond
+
+// pendingJob is a job which was still running when its tool returned, the
+// manager sends the result to the buffered channel, so nothing blocks if
+// the result is never read
+type pendingJob struct {
+	unit   string
+	result <-chan string
+}
+
+// pendingJobs are the jobs which were still running when their tool
+// returned, by age and with one job per unit
+type pendingJobs struct {
+	mu   sync.Mutex
+	jobs []pendingJob
+}
+
+// add tracks the job of the unit, it replaces an older job of the unit
+func (p *pendingJobs) add(unit string, result <-chan string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jobs = slices.DeleteFunc(p.jobs, func(job pendingJob) bool { return job.unit == unit })
+	p.jobs = append(p.jobs, pendingJob{unit: unit, result: result})
+	if len(p.jobs) > maxPendingJobs {
+		p.jobs = slices.Delete(p.jobs, 0, len(p.jobs)-maxPendingJobs)
+	}
+}
+
+// collect removes the finished jobs and returns their results and the
+// units of the jobs which are still running
+func (p *pendingJobs) collect() (finished []string, running []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jobs = slices.DeleteFunc(p.jobs, func(job pendingJob) bool {
+		select {
+		case result := <-job.result:
+			finished = append(finished, fmt.Sprintf("%s: %s", job.unit, result))
+			return true
+		default:
+			running = append(running, job.unit)
+			return false
+		}
+	})
+	return finished, running
+}
+
+// jobFunc is a method of the manager which creates a job for the unit
+type jobFunc func(ctx context.Context, name string, mode string, ch chan<- string) (int, error)
+
+// jobResult returns the result of a job or the message if it's still
+// running
+func jobResult(result string, inProgress string) *mcp.CallToolResult {
+	if result == "" {
+		result = inProgress
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: result,
 			},
-		}, nil, nil
+		},
+	}
+}
+
+// waitJob waits timeOut seconds, 3 by default, for the result of the job
+// and returns "" if the job is still running
+func waitJob(jobChan <-chan string, timeOut uint) string {
+	if timeOut == 0 {
+		timeOut = 3
+	}
+	select {
+	case result := <-jobChan:
+		return result
+	case <-time.After(time.Duration(timeOut) * time.Second):
+		return ""
+	}
+}
+
+// checkStartLimit returns an error if the unit failed because its start
+// rate limit was hit
+func (conn *Connection) checkStartLimit(ctx context.Context, name string) error {
+	props, err := conn.dbus.GetAllPropertiesContext(ctx, name)
+	if err != nil {
+		return nil
+	}
+	if result, _ := props["Result"].(string); result == "start-limit-hit" {
+		burst, _ := props["StartLimitBurst"].(uint32)
+		interval, _ := props["StartLimitIntervalUSec"].(uint64)
+		return fmt.Errorf("%s failed to start because it was started more than %d times within %s (start-limit-hit), reset the failed state with reset_failed and start the unit again",
+			name, burst, time.Duration(interval)*time.Microsecond)
+	}
+	return nil
+}
+
+type CheckReloadRestartParams struct {
+	TimeOut uint `json:"timeout" jsonschema:"Time to wait for the restart or reload to finish. After the timeout the function will return and restart and reload will run in the background and the result can be retreived with a separate function."`
+}
+
+// check status of reload or restart, returns the results of the jobs
+// which were still running when their tool returned
+func (conn *Connection) CheckForRestartReloadRunning(ctx context.Context, req *mcp.CallToolRequest, params *RestartReloadParams) (res *mcp.CallToolResult, _ any, err error) {
+	if params.TimeOut > MaxTimeOut {
+		return nil, nil, fmt.Errorf("not waiting longer than MaxTimeOut(%d), longer operation will run in the background and result can be gathered with separate function.", MaxTimeOut)
+	}
+	timeOut := params.TimeOut
+	if timeOut == 0 {
+		timeOut = 3
+	}
+	deadline := time.Now().Add(time.Duration(timeOut) * time.Second)
+	for {
+		finished, running := conn.jobs.collect()
+		if len(finished) == 0 && len(running) == 0 {
+			return jobResult("", "No start, stop, reload or restart job in progress."), nil, nil
+		}
+		if len(finished) > 0 || !time.Now().Before(deadline) {
+			if len(running) > 0 {
+				finished = append(finished, "Still in progress: "+strings.Join(running, ", "))
+			}
+			return jobResult(strings.Join(finished, "\n"), ""), nil, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(jobPollInterval):
+		}
 	}
 }
 
@@ -368,24 +508,94 @@ func (conn *Connection) StopUnit(ctx context.Context, req *mcp.CallToolRequest,
 		return nil, nil, fmt.Errorf("not waiting longer than MaxTimeOut(%d), longer operation will run in the background and result can be gathered with separate function.", MaxTimeOut)
 	}
 	if params.Kill {
-		conn.dbus.KillUnitContext(ctx, params.Name, int32(9))
+		// killing doesn't create a job, so there is nothing to wait for
+		return conn.KillUnit(ctx, req, &KillUnitParams{
+			Name:   params.Name,
+			Signal: "SIGKILL",
+		})
+	}
+	result, err := conn.runJob(ctx, conn.dbus.StopUnitContext, params.Name, params.Mode, params.TimeOut)
+	if err != nil {
+		return nil, nil, err
+	}
+	return jobResult(result, "Stop still in progress."), nil, nil
+}
+
+type ResetFailedParams struct {
+	Names []string `json:"names,omitempty" jsonschema:"Names or patterns of the units to reset, like 'foo.service' or 'foo*'. Without names all failed units are reset. This parameter is optional."`
+}
+
+// reset the failed state and the start rate limit counter of units
+func (conn *Connection) ResetFailed(ctx context.Context, req *mcp.CallToolRequest, params *ResetFailedParams) (res *mcp.CallToolResult, _ any, err error) {
+	var units []dbus.UnitStatus
+	if len(params.Names) == 0 {
+		units, err = conn.dbus.ListUnitsFilteredContext(ctx, []string{"failed"})
 	} else {
-		_, err = conn.dbus.StopUnitContext(ctx, params.Name, params.Mode, conn.rchannel)
+		units, err = conn.dbus.ListUnitsByPatternsContext(ctx, []string{}, params.Names)
 	}
 	if err != nil {
 		return nil, nil, err
 	}
-	return conn.CheckForRestartReloadRunning(ctx, req, &RestartReloadParams{
-		TimeOut: params.TimeOut,
-	})
+	if len(units) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: "no units to reset",
+				},
+			},
+		}, nil, nil
+	}
+	txtContentList := []mcp.Content{}
+	for _, u := range units {
+		resJson := struct {
+			Name        string `json:"name"`
+			ActiveState string `json:"active_state"`
+			Reset       bool   `json:"reset"`
+			Error       string `json:"error,omitempty"`
+		}{
+			Name:        u.Name,
+			ActiveState: u.ActiveState,
+			Reset:       true,
+		}
+		if err := conn.dbus.ResetFailedUnitContext(ctx, u.Name); err != nil {
+			resJson.Reset = false
+			resJson.Error = err.Error()
+		}
+		jsonByte, err := json.Marshal(resJson)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+		}
+		txtContentList = append(txtContentList, &mcp.TextContent{
+			Text: string(jsonByte),
+		})
+	}
+	return &mcp.CallToolResult{
+		Content: txtContentList,
+	}, nil, nil
 }
 
 type EnableParams struct {
 	File    string `json:"file" jsonschema:"Name of the service or unit if the unit is in the standard location. Takes the absolute path if the unit or service is not placed under '/etc/' or '/usr/lib/systemd'. Does not take wildcards. For the service foo, this would be 'foo.service' if foo is installed by a package."`
-	Disable bool   `json"disable" jsonschema:"Set to true to disable the unit instead of enable."`
+	Disable bool   `json:"disable" jsonschema:"Set to true to disable the unit instead of enable."`
+	Now     bool   `json:"now,omitempty" jsonschema:"Also start the unit when enabling it, or stop it when disabling it."`
+	Runtime bool   `json:"runtime,omitempty" jsonschema:"Only enable or disable the unit until the next reboot, the symlinks are changed in /run instead of /etc."`
+	NoForce bool   `json:"no_force,omitempty" jsonschema:"Don't overwrite existing symlinks which conflict with the enablement, fail instead. By default they are overwritten."`
+	TimeOut uint   `json:"timeout,omitempty" jsonschema:"Time to wait for the start or stop with 'now' to finish. Defaults to 3 seconds."`
+}
+
+// EnableResult describes the start or stop of a unit which was done
+// together with its enablement
+type EnableResult struct {
+	Unit   string `json:"unit"`
+	Action string `json:"action"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
 }
 
 func (conn *Connection) EnableDisableUnit(ctx context.Context, req *mcp.CallToolRequest, params *EnableParams) (res *mcp.CallToolResult, _ any, err error) {
+	if params.TimeOut > MaxTimeOut {
+		return nil, nil, fmt.Errorf("not waiting longer than MaxTimeOut(%d), longer operation will run in the background and result can be gathered with separate function.", MaxTimeOut)
+	}
 	if params.Disable {
 		return conn.DisableUnit(ctx, req, params)
 	} else {
@@ -394,81 +604,72 @@ func (conn *Connection) EnableDisableUnit(ctx context.Context, req *mcp.CallTool
 }
 
 func (conn *Connection) EnableUnit(ctx context.Context, req *mcp.CallToolRequest, params *EnableParams) (res *mcp.CallToolResult, _ any, err error) {
-	_, enabledRes, err := conn.dbus.EnableUnitFilesContext(ctx, []string{params.File}, false, true)
+	_, enabledRes, err := conn.dbus.EnableUnitFilesContext(ctx, []string{params.File}, params.Runtime, !params.NoForce)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error when enabling: %w", err)
 	}
-	if len(enabledRes) == 0 {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Text: fmt.Sprintf("nothing changed for %s", params.File),
-				},
-			},
-		}, nil, nil
-	} else {
-		txtContentList := []mcp.Content{}
-		for _, res := range enabledRes {
-			resJson := struct {
-				Type        string `json:"type"`
-				Filename    string `json:"filename"`
-				Destination string `json:"destination"`
-			}{
-				Type:        res.Type,
-				Filename:    res.Filename,
-				Destination: res.Destination,
-			}
-			jsonByte, err := json.Marshal(resJson)
-			if err != nil {
-				return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
-			}
-			txtContentList = append(txtContentList, &mcp.TextContent{
-				Text: string(jsonByte),
-			})
-		}
-		return &mcp.CallToolResult{
-			Content: txtContentList,
-		}, nil, nil
+	res, _, err = unitFileChangesResult([]string{params.File}, toUnitFileChanges(enabledRes))
+	if err != nil || !params.Now {
+		return res, nil, err
 	}
+	return conn.addNowResult(ctx, res, true, params)
 }
 
 func (conn *Connection) DisableUnit(ctx context.Context, req *mcp.CallToolRequest, params *EnableParams) (res *mcp.CallToolResult, _ any, err error) {
-	disabledRes, err := conn.dbus.DisableUnitFilesContext(ctx, []string{params.File}, false)
+	disabledRes, err := conn.dbus.DisableUnitFilesContext(ctx, []string{params.File}, params.Runtime)
 	if err != nil {
 		return nil, nil, fmt.Errorf("error when disabling: %w", err)
 	}
-	if len(disabledRes) == 0 {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Text: fmt.Sprintf("nothing changed for %s", params.File),
-				},
-			},
-		}, nil, nil
-	} else {
-		txtContentList := []mcp.Content{}
-		for _, res := range disabledRes {
-			resJson := struct {
-				Type        string `json:"type"`
-				Filename    string `json:"filename"`
-				Destination string `json:"destination"`
-			}{
-				Type:        res.Type,
-				Filename:    res.Filename,
-				Destination: res.Destination,
-			}
-			jsonByte, err := json.Marshal(resJson)
-			if err != nil {
-				return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+	res, _, err = unitFileChangesResult([]string{params.File}, toUnitFileChanges(disabledRes))
+	if err != nil || !params.Now {
+		return res, nil, err
+	}
+	return conn.addNowResult(ctx, res, false, params)
+}
+
+// addNowResult starts or stops the unit after it was enabled or disabled
+// and adds the result. As the enablement can't be rolled back, a failed
+// start or stop is reported as partial failure.
+func (conn *Connection) addNowResult(ctx context.Context, res *mcp.CallToolResult, start bool, params *EnableParams) (*mcp.CallToolResult, any, error) {
+	nowRes := EnableResult{
+		Unit:   path.Base(params.File),
+		Action: "stop",
+	}
+	if start {
+		nowRes.Action = "start"
+	}
+	job := conn.dbus.StopUnitContext
+	if start {
+		job = conn.dbus.StartUnitContext
+	}
+	result, err := conn.runJob(ctx, job, nowRes.Unit, "replace", params.TimeOut)
+	switch {
+	case err != nil:
+		nowRes.Result = "error"
+		nowRes.Error = err.Error()
+		if limitErr := conn.checkStartLimit(ctx, nowRes.Unit); start && limitErr != nil {
+			nowRes.Error = limitErr.Error()
+		}
+	case result == "":
+		nowRes.Result = "in progress"
+	default:
+		nowRes.Result = result
+		if result != "done" {
+			nowRes.Error = fmt.Sprintf("the unit file change was made, but the %s job finished with result '%s', use diagnose_unit to get the reason", nowRes.Action, result)
+			if limitErr := conn.checkStartLimit(ctx, nowRes.Unit); start && limitErr != nil {
+				nowRes.Error = limitErr.Error()
 			}
-			txtContentList = append(txtContentList, &mcp.TextContent{
-				Text: string(jsonByte),
-			})
 		}
-		return &mcp.CallToolResult{
-			Content: txtContentList,
-		}, nil, nil
 	}
+	jsonByte, err := json.Marshal(nowRes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not unmarshall result: %w", err)
+	}
+	res.Content = append(res.Content, &mcp.TextContent{
+		Text: string(jsonByte),
+	})
+	res.IsError = nowRes.Error != ""
+	return res, nil, nil
 }
 
 type ListUnitFilesParams struct {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/coreos/go-systemd/v22/dbus"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -16,6 +17,25 @@ type mockDbusConnection struct {
 	listUnitsFiltered   func(states []string) ([]dbus.UnitStatus, error)
 	listUnitsByPatterns func(patterns []string, states []string) ([]dbus.UnitStatus, error)
 	getAllProperties    func(unitName string) (map[string]interface{}, error)
+	startUnit           func(name string, mode string, ch chan<- string) (int, error)
+	resetFailedUnit     func(name string) error
+	maskUnitFiles       func(files []string, runtime bool, force bool) ([]dbus.MaskUnitFileChange, error)
+	presetAllUnitFiles  func(mode string, runtime bool, force bool) ([]dbus.EnableUnitFileChange, error)
+	enableUnitFiles     func(files []string, runtime bool, force bool) (bool, []dbus.EnableUnitFileChange, error)
+	reload              func() error
+	setUnitProperties   func(name string, runtime bool, properties ...dbus.Property) error
+	startTransientUnit  func(name string, mode string, properties []dbus.Property, ch chan<- string) (int, error)
+	stopUnit            func(name string, mode string, ch chan<- string) (int, error)
+	startTransientAux   func(name string, mode string, properties []dbus.Property, aux []AuxUnit) (string, error)
+	managerProperties   func() (map[string]interface{}, error)
+	killUnitWithTarget  func(name string, target dbus.Who, signal int32) error
+	freezeUnit          func(name string) error
+	thawUnit            func(name string) error
+	cleanUnit           func(name string, mask []string) error
+	getDefaultTarget    func() (string, error)
+	getUnitFileState    func(name string) (string, error)
+	setDefaultTarget    func(name string, force bool) ([]dbus.EnableUnitFileChange, error)
+	reexecute           func() error
 }
 
 func (m *mockDbusConnection) ListUnitsContext(ctx context.Context) ([]dbus.UnitStatus, error) {
@@ -34,6 +54,82 @@ func (m *mockDbusConnection) GetAllPropertiesContext(ctx context.Context, unitNa
 	return m.getAllProperties(unitName)
 }
 
+func (m *mockDbusConnection) StartUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return m.startUnit(name, mode, ch)
+}
+
+func (m *mockDbusConnection) ResetFailedUnitContext(ctx context.Context, name string) error {
+	return m.resetFailedUnit(name)
+}
+
+func (m *mockDbusConnection) MaskUnitFilesContext(ctx context.Context, files []string, runtime bool, force bool) ([]dbus.MaskUnitFileChange, error) {
+	return m.maskUnitFiles(files, runtime, force)
+}
+
+func (m *mockDbusConnection) PresetAllUnitFilesContext(ctx context.Context, mode string, runtime bool, force bool) ([]dbus.EnableUnitFileChange, error) {
+	return m.presetAllUnitFiles(mode, runtime, force)
+}
+
+func (m *mockDbusConnection) EnableUnitFilesContext(ctx context.Context, files []string, runtime bool, force bool) (bool, []dbus.EnableUnitFileChange, error) {
+	return m.enableUnitFiles(files, runtime, force)
+}
+
+func (m *mockDbusConnection) ReloadContext(ctx context.Context) error {
+	return m.reload()
+}
+
+func (m *mockDbusConnection) SetUnitPropertiesContext(ctx context.Context, name string, runtime bool, properties ...dbus.Property) error {
+	return m.setUnitProperties(name, runtime, properties...)
+}
+
+func (m *mockDbusConnection) StartTransientUnitContext(ctx context.Context, name string, mode string, properties []dbus.Property, ch chan<- string) (int, error) {
+	return m.startTransientUnit(name, mode, properties, ch)
+}
+
+func (m *mockDbusConnection) StopUnitContext(ctx context.Context, name string, mode string, ch chan<- string) (int, error) {
+	return m.stopUnit(name, mode, ch)
+}
+
+func (m *mockDbusConnection) StartTransientUnitAuxContext(ctx context.Context, name string, mode string, properties []dbus.Property, aux []AuxUnit) (string, error) {
+	return m.startTransientAux(name, mode, properties, aux)
+}
+
+func (m *mockDbusConnection) GetManagerPropertiesContext(ctx context.Context) (map[string]interface{}, error) {
+	return m.managerProperties()
+}
+
+func (m *mockDbusConnection) KillUnitWithTarget(ctx context.Context, name string, target dbus.Who, signal int32) error {
+	return m.killUnitWithTarget(name, target, signal)
+}
+
+func (m *mockDbusConnection) FreezeUnit(ctx context.Context, unit string) error {
+	return m.freezeUnit(unit)
+}
+
+func (m *mockDbusConnection) ThawUnit(ctx context.Context, unit string) error {
+	return m.thawUnit(unit)
+}
+
+func (m *mockDbusConnection) CleanUnitContext(ctx context.Context, name string, mask []string) error {
+	return m.cleanUnit(name, mask)
+}
+
+func (m *mockDbusConnection) GetUnitFileStateContext(ctx context.Context, name string) (string, error) {
+	return m.getUnitFileState(name)
+}
+
+func (m *mockDbusConnection) GetDefaultTargetContext(ctx context.Context) (string, error) {
+	return m.getDefaultTarget()
+}
+
+func (m *mockDbusConnection) SetDefaultTargetContext(ctx context.Context, name string, force bool) ([]dbus.EnableUnitFileChange, error) {
+	return m.setDefaultTarget(name, force)
+}
+
+func (m *mockDbusConnection) ReexecuteContext(ctx context.Context) error {
+	return m.reexecute()
+}
+
 func TestListUnitHandlerNameState(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -57,7 +153,7 @@ func TestListUnitHandlerNameState(t *testing.T) {
 			},
 			want: []mcp.Content{
 				&mcp.TextContent{
-					Text: `{"Id":"test.service","Description":"","LoadState":"","FragmentPath":"","UnitFileState":"","UnitFilePreset":"","ActiveState":"","SubState":"","ActiveEnterTimestamp":0,"InvocationID":"","MainPID":0,"ExecMainPID":0,"ExecMainStatus":0,"TasksCurrent":0,"TasksMax":0,"CPUUsageNSec":0,"ControlGroup":"","ExecStartPre":null,"ExecStart":null,"Restart":"","MemoryCurrent":0}`,
+					Text: `{"Id":"test.service","Description":"","LoadState":"","FragmentPath":"","UnitFileState":"","UnitFilePreset":"","ActiveState":"","SubState":"","FreezerState":"","ActiveEnterTimestamp":0,"InvocationID":"","MainPID":0,"ExecMainPID":0,"ExecMainStatus":0,"TasksCurrent":0,"TasksMax":0,"CPUUsageNSec":0,"ControlGroup":"","ExecStartPre":null,"ExecStart":null,"Restart":"","MemoryCurrent":0}`,
 				},
 			},
 			wantErr: false,
@@ -108,10 +204,10 @@ func TestListUnitHandlerNameState(t *testing.T) {
 			},
 			want: []mcp.Content{
 				&mcp.TextContent{
-					Text: `{"Id":"test1.service","Description":"","LoadState":"","FragmentPath":"","UnitFileState":"","UnitFilePreset":"","ActiveState":"","SubState":"","ActiveEnterTimestamp":0,"InvocationID":"","MainPID":0,"ExecMainPID":0,"ExecMainStatus":0,"TasksCurrent":0,"TasksMax":0,"CPUUsageNSec":0,"ControlGroup":"","ExecStartPre":null,"ExecStart":null,"Restart":"","MemoryCurrent":0}`,
+					Text: `{"Id":"test1.service","Description":"","LoadState":"","FragmentPath":"","UnitFileState":"","UnitFilePreset":"","ActiveState":"","SubState":"","FreezerState":"","ActiveEnterTimestamp":0,"InvocationID":"","MainPID":0,"ExecMainPID":0,"ExecMainStatus":0,"TasksCurrent":0,"TasksMax":0,"CPUUsageNSec":0,"ControlGroup":"","ExecStartPre":null,"ExecStart":null,"Restart":"","MemoryCurrent":0}`,
 				},
 				&mcp.TextContent{
-					Text: `{"Id":"test2.service","Description":"","LoadState":"","FragmentPath":"","UnitFileState":"","UnitFilePreset":"","ActiveState":"","SubState":"","ActiveEnterTimestamp":0,"InvocationID":"","MainPID":0,"ExecMainPID":0,"ExecMainStatus":0,"TasksCurrent":0,"TasksMax":0,"CPUUsageNSec":0,"ControlGroup":"","ExecStartPre":null,"ExecStart":null,"Restart":"","MemoryCurrent":0}`,
+					Text: `{"Id":"test2.service","Description":"","LoadState":"","FragmentPath":"","UnitFileState":"","UnitFilePreset":"","ActiveState":"","SubState":"","FreezerState":"","ActiveEnterTimestamp":0,"InvocationID":"","MainPID":0,"ExecMainPID":0,"ExecMainStatus":0,"TasksCurrent":0,"TasksMax":0,"CPUUsageNSec":0,"ControlGroup":"","ExecStartPre":null,"ExecStart":null,"Restart":"","MemoryCurrent":0}`,
 				},
 			},
 			wantErr: false,
@@ -130,7 +226,7 @@ func TestListUnitHandlerNameState(t *testing.T) {
 			},
 			want: []mcp.Content{
 				&mcp.TextContent{
-					Text: `{"Id":"test.service","Description":"","LoadState":"","FragmentPath":"","UnitFileState":"","UnitFilePreset":"","ActiveState":"","SubState":"","ActiveEnterTimestamp":0,"InvocationID":"","MainPID":0,"ExecMainPID":0,"ExecMainStatus":0,"TasksCurrent":0,"TasksMax":0,"CPUUsageNSec":0,"ControlGroup":"","ExecStartPre":null,"ExecStart":null,"Restart":"","MemoryCurrent":0}`,
+					Text: `{"Id":"test.service","Description":"","LoadState":"","FragmentPath":"","UnitFileState":"","UnitFilePreset":"","ActiveState":"","SubState":"","FreezerState":"","ActiveEnterTimestamp":0,"InvocationID":"","MainPID":0,"ExecMainPID":0,"ExecMainStatus":0,"TasksCurrent":0,"TasksMax":0,"CPUUsageNSec":0,"ControlGroup":"","ExecStartPre":null,"ExecStart":null,"Restart":"","MemoryCurrent":0}`,
 				},
 			},
 			wantErr: false,
@@ -166,3 +262,138 @@ func TestListUnitHandlerNameState(t *testing.T) {
 		})
 	}
 }
+
+func TestStartUnitStartLimitHit(t *testing.T) {
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			startUnit: func(name string, mode string, ch chan<- string) (int, error) {
+				ch <- "failed"
+				return 1, nil
+			},
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				return map[string]interface{}{"Result": "start-limit-hit", "StartLimitBurst": uint32(5), "StartLimitIntervalUSec": uint64(10000000)}, nil
+			},
+		},
+	}
+	_, _, err := conn.StartUnit(context.Background(), nil, &RestartReloadParams{Name: "test.service"})
+	assert.ErrorContains(t, err, "start-limit-hit")
+	assert.ErrorContains(t, err, "reset_failed")
+
+	conn.dbus.(*mockDbusConnection).startUnit = func(name string, mode string, ch chan<- string) (int, error) {
+		ch <- "done"
+		return 1, nil
+	}
+	res, _, err := conn.StartUnit(context.Background(), nil, &RestartReloadParams{Name: "test.service"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Started test.service", res.Content[0].(*mcp.TextContent).Text)
+}
+
+func TestCheckRestartReloadAfterTimeout(t *testing.T) {
+	oldInterval := jobPollInterval
+	jobPollInterval = time.Millisecond
+	defer func() { jobPollInterval = oldInterval }()
+	jobs := map[string]chan<- string{}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			startUnit: func(name string, mode string, ch chan<- string) (int, error) {
+				jobs[name] = ch
+				return 1, nil
+			},
+		},
+	}
+	check := func() string {
+		res, _, err := conn.CheckForRestartReloadRunning(context.Background(), nil, &RestartReloadParams{TimeOut: 1})
+		assert.NoError(t, err)
+		return res.Content[0].(*mcp.TextContent).Text
+	}
+	assert.Equal(t, "No start, stop, reload or restart job in progress.", check())
+
+	for _, name := range []string{"slow.service", "slower.service"} {
+		res, _, err := conn.StartUnit(context.Background(), nil, &RestartReloadParams{Name: name, TimeOut: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, "Start still in progress.", res.Content[0].(*mcp.TextContent).Text)
+	}
+	// the jobs are still running, so they must not be reported as finished
+	assert.Equal(t, "Still in progress: slow.service, slower.service", check())
+
+	jobs["slow.service"] <- "done"
+	assert.Equal(t, "slow.service: done\nStill in progress: slower.service", check())
+	jobs["slower.service"] <- "failed"
+	assert.Equal(t, "slower.service: failed", check())
+	assert.Equal(t, "No start, stop, reload or restart job in progress.", check())
+}
+
+func TestPendingJobsBounded(t *testing.T) {
+	var jobs pendingJobs
+	for i := 0; i < maxPendingJobs+10; i++ {
+		jobs.add(fmt.Sprintf("unit%d.service", i), make(chan string, 1))
+	}
+	// a new job of a unit replaces the old one
+	jobs.add("unit20.service", make(chan string, 1))
+	_, running := jobs.collect()
+	assert.Len(t, running, maxPendingJobs)
+	assert.Equal(t, "unit10.service", running[0])
+	assert.Equal(t, "unit20.service", running[len(running)-1])
+}
+
+func TestResetFailed(t *testing.T) {
+	reset := []string{}
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			listUnitsFiltered: func(states []string) ([]dbus.UnitStatus, error) {
+				assert.Equal(t, []string{"failed"}, states)
+				return []dbus.UnitStatus{{Name: "a.service", ActiveState: "failed"}, {Name: "b.service", ActiveState: "failed"}}, nil
+			},
+			resetFailedUnit: func(name string) error {
+				if name == "b.service" {
+					return fmt.Errorf("access denied")
+				}
+				reset = append(reset, name)
+				return nil
+			},
+		},
+	}
+	res, _, err := conn.ResetFailed(context.Background(), nil, &ResetFailedParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.service"}, reset)
+	assert.Len(t, res.Content, 2)
+	assert.JSONEq(t, `{"name":"a.service","active_state":"failed","reset":true}`, res.Content[0].(*mcp.TextContent).Text)
+	assert.JSONEq(t, `{"name":"b.service","active_state":"failed","reset":false,"error":"access denied"}`, res.Content[1].(*mcp.TextContent).Text)
+}
+
+func TestEnableUnitNow(t *testing.T) {
+	mock := &mockDbusConnection{
+		enableUnitFiles: func(files []string, runtime bool, force bool) (bool, []dbus.EnableUnitFileChange, error) {
+			assert.True(t, runtime)
+			// conflicting symlinks are overwritten by default
+			assert.True(t, force)
+			return true, []dbus.EnableUnitFileChange{{Type: "symlink", Filename: "/run/systemd/system/multi-user.target.wants/test.service", Destination: "/usr/lib/systemd/system/test.service"}}, nil
+		},
+		startUnit: func(name string, mode string, ch chan<- string) (int, error) {
+			assert.Equal(t, "test.service", name)
+			ch <- "done"
+			return 1, nil
+		},
+		getAllProperties: func(unitName string) (map[string]interface{}, error) {
+			return map[string]interface{}{"Result": "exit-code"}, nil
+		},
+	}
+	conn := &Connection{dbus: mock}
+	params := &EnableParams{File: "/usr/lib/systemd/system/test.service", Now: true, Runtime: true}
+	res, _, err := conn.EnableDisableUnit(context.Background(), nil, params)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Len(t, res.Content, 2)
+	assert.JSONEq(t, `{"unit":"test.service","action":"start","result":"done"}`, res.Content[1].(*mcp.TextContent).Text)
+
+	// the unit is enabled, but the start fails
+	mock.startUnit = func(name string, mode string, ch chan<- string) (int, error) {
+		ch <- "failed"
+		return 1, nil
+	}
+	res, _, err = conn.EnableDisableUnit(context.Background(), nil, params)
+	assert.NoError(t, err)
+	assert.True(t, res.IsError)
+	assert.Len(t, res.Content, 2)
+	assert.Contains(t, res.Content[1].(*mcp.TextContent).Text, `"result":"failed"`)
+}
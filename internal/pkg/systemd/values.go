@@ -0,0 +1,267 @@
+package systemd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Infinity is used by systemd for unset limits
+const Infinity = math.MaxUint64
+
+// ParseBoolean parses a boolean value as systemd does
+func ParseBoolean(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "yes", "y", "true", "t", "on":
+		return true, nil
+	case "0", "no", "n", "false", "f", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean: %s", value)
+}
+
+// size suffixes with base 1024, see parse_size() of systemd
+var sizeSuffixes = map[string]uint64{
+	"":  1,
+	"B": 1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+	"P": 1 << 50,
+	"E": 1 << 60,
+}
+
+// ParseSize parses a size in bytes with an optional suffix like 512M or
+// 1.5G, 'infinity' returns Infinity
+func ParseSize(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+	if value == "infinity" {
+		return Infinity, nil
+	}
+	num, suffix := splitNumber(value)
+	factor, ok := sizeSuffixes[suffix]
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid size: %s", value)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size: %s", value)
+	}
+	size := f * float64(factor)
+	if size >= Infinity {
+		return 0, fmt.Errorf("size out of range: %s", value)
+	}
+	return uint64(size), nil
+}
+
+// ParsePercent parses a percentage like 20% or 12.5%
+func ParsePercent(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	num, ok := strings.CutSuffix(value, "%")
+	if !ok {
+		return 0, fmt.Errorf("invalid percentage, missing %%: %s", value)
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid percentage: %s", value)
+	}
+	return f, nil
+}
+
+// time span units, see systemd.time(7)
+var timeSpanUnits = map[string]time.Duration{
+	"usec":    time.Microsecond,
+	"us":      time.Microsecond,
+	"µs":      time.Microsecond,
+	"msec":    time.Millisecond,
+	"ms":      time.Millisecond,
+	"seconds": time.Second,
+	"second":  time.Second,
+	"sec":     time.Second,
+	"s":       time.Second,
+	"minutes": time.Minute,
+	"minute":  time.Minute,
+	"min":     time.Minute,
+	"m":       time.Minute,
+	"hours":   time.Hour,
+	"hour":    time.Hour,
+	"hr":      time.Hour,
+	"h":       time.Hour,
+	"days":    24 * time.Hour,
+	"day":     24 * time.Hour,
+	"d":       24 * time.Hour,
+	"weeks":   7 * 24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+	"w":       7 * 24 * time.Hour,
+	"months":  2629800 * time.Second,
+	"month":   2629800 * time.Second,
+	"M":       2629800 * time.Second,
+	"years":   31557600 * time.Second,
+	"year":    31557600 * time.Second,
+	"y":       31557600 * time.Second,
+}
+
+// nanoTimeSpanUnits are the units which are only accepted by the settings
+// in nanoseconds like TimerSlackNSec=, see parse_nsec() of systemd
+var nanoTimeSpanUnits = map[string]time.Duration{
+	"nsec": time.Nanosecond,
+	"ns":   time.Nanosecond,
+}
+
+// ParseTimeSpan parses a time span like '5min 30s' or '1h', a number
+// without unit is in seconds. 'infinity' returns Infinity.
+func ParseTimeSpan(value string) (usec uint64, err error) {
+	nsec, err := parseTimeSpan(value, time.Second, false)
+	if err != nil || nsec == Infinity {
+		return nsec, err
+	}
+	return nsec / uint64(time.Microsecond), nil
+}
+
+// ParseNanoTimeSpan parses a time span of a setting in nanoseconds, which
+// takes the units ns and nsec as well and a number without unit is in
+// nanoseconds. 'infinity' returns Infinity.
+func ParseNanoTimeSpan(value string) (nsec uint64, err error) {
+	return parseTimeSpan(value, time.Nanosecond, true)
+}
+
+// parseTimeSpan parses a time span into nanoseconds
+func parseTimeSpan(value string, defaultUnit time.Duration, nano bool) (nsec uint64, err error) {
+	value = strings.TrimSpace(value)
+	if value == "infinity" {
+		return Infinity, nil
+	}
+	if value == "" {
+		return 0, fmt.Errorf("empty time span")
+	}
+	rest := value
+	for rest != "" {
+		num, tail := splitNumber(rest)
+		if num == "" {
+			return 0, fmt.Errorf("invalid time span: %s", value)
+		}
+		tail = strings.TrimLeft(tail, " ")
+		unit := tail
+		if i := strings.IndexFunc(tail, func(r rune) bool { return unicode.IsDigit(r) || r == ' ' || r == '.' }); i >= 0 {
+			unit, tail = tail[:i], tail[i:]
+		} else {
+			tail = ""
+		}
+		factor := defaultUnit
+		if unit != "" {
+			var ok bool
+			if factor, ok = timeSpanUnits[unit]; !ok && nano {
+				factor, ok = nanoTimeSpanUnits[unit]
+			}
+			if !ok {
+				return 0, fmt.Errorf("invalid time span unit %s in: %s", unit, value)
+			}
+		}
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("invalid time span: %s", value)
+		}
+		nsec += uint64(f * float64(factor))
+		rest = strings.TrimLeft(tail, " ")
+	}
+	return nsec, nil
+}
+
+// splitNumber splits the leading decimal number from the rest of the value
+func splitNumber(value string) (num, rest string) {
+	i := strings.IndexFunc(value, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' })
+	if i < 0 {
+		return value, ""
+	}
+	return value[:i], value[i:]
+}
+
+// cEscapes are the single character escapes of the C-style escaping
+var cEscapes = map[byte]rune{
+	'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', 'v': '\v',
+	's': ' ', '\\': '\\', '"': '"', '\'': '\'',
+}
+
+// SplitWords splits a value into whitespace separated words with the
+// quoting and C-style escapes of systemd, like the assignments of
+// Environment= or a command line, see extract_first_word() of systemd
+func SplitWords(value string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(value) {
+				return nil, fmt.Errorf("trailing backslash in: %s", value)
+			}
+			r, n, err := unescapeC(value[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("%w in: %s", err, value)
+			}
+			word.WriteRune(r)
+			inWord = true
+			i += n
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in: %s", value)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// unescapeC decodes the escape sequence after a backslash and returns the
+// character and the number of bytes of the sequence
+func unescapeC(seq string) (rune, int, error) {
+	if r, ok := cEscapes[seq[0]]; ok {
+		return r, 1, nil
+	}
+	base, digits, skip := 0, 0, 1
+	switch seq[0] {
+	case 'x':
+		base, digits = 16, 2
+	case 'u':
+		base, digits = 16, 4
+	case 'U':
+		base, digits = 16, 8
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		base, digits, skip = 8, 3, 0
+	default:
+		return 0, 0, fmt.Errorf("invalid escape \\%c", seq[0])
+	}
+	if len(seq) < skip+digits {
+		return 0, 0, fmt.Errorf("short escape \\%s", seq)
+	}
+	code, err := strconv.ParseUint(seq[skip:skip+digits], base, 32)
+	if err != nil || code == 0 {
+		return 0, 0, fmt.Errorf("invalid escape \\%s", seq[:skip+digits])
+	}
+	return rune(code), skip + digits, nil
+}
@@ -0,0 +1,90 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	for value, want := range map[string]uint64{
+		"1024":     1024,
+		"512M":     512 << 20,
+		"1.5G":     3 << 29,
+		"2T":       2 << 40,
+		"infinity": Infinity,
+	} {
+		got, err := ParseSize(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	for _, value := range []string{"", "M", "12X", "-1G", "1.2.3K"} {
+		_, err := ParseSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseTimeSpan(t *testing.T) {
+	for value, want := range map[string]uint64{
+		"30":        30000000,
+		"5min 30s":  330000000,
+		"1h30min":   5400000000,
+		"500ms":     500000,
+		"2 days":    172800000000,
+		"infinity":  Infinity,
+		"1.5s 10us": 1500010,
+	} {
+		got, err := ParseTimeSpan(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	for _, value := range []string{"", "5 lightyears", "min", "-5s", "50ns"} {
+		_, err := ParseTimeSpan(value)
+		assert.Error(t, err, value)
+	}
+	for value, want := range map[string]uint64{
+		"50":        50,
+		"50ns":      50,
+		"1us 5nsec": 1005,
+		"2ms":       2000000,
+	} {
+		got, err := ParseNanoTimeSpan(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+}
+
+func TestParsePercentAndBoolean(t *testing.T) {
+	percent, err := ParsePercent("12.5%")
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, percent)
+	_, err = ParsePercent("50")
+	assert.Error(t, err)
+	b, err := ParseBoolean("yes")
+	assert.NoError(t, err)
+	assert.True(t, b)
+	b, err = ParseBoolean("off")
+	assert.NoError(t, err)
+	assert.False(t, b)
+	_, err = ParseBoolean("maybe")
+	assert.Error(t, err)
+}
+
+func TestSplitWords(t *testing.T) {
+	for value, want := range map[string][]string{
+		"":                              {},
+		"FOO=1  BAR=2":                  {"FOO=1", "BAR=2"},
+		`"FOO=a b" 'BAR=c "d"'`:         {"FOO=a b", `BAR=c "d"`},
+		`FOO="a b"c`:                    {"FOO=a bc"},
+		`A=\x41\101\u00e4 B=\"q\" C=\s`: {"A=AAä", `B="q"`, "C= "},
+		`""`:                            {""},
+	} {
+		got, err := SplitWords(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+	for _, value := range []string{`"open`, `trailing\`, `\q`, `\x4`, `\x00`} {
+		_, err := SplitWords(value)
+		assert.Error(t, err, value)
+	}
+}
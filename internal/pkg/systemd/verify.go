@@ -0,0 +1,453 @@
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// severities of the verification findings
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// booleanDirectives are the settings which take a boolean
+var booleanDirectives = []string{
+	"DefaultDependencies", "IgnoreOnIsolate", "StopWhenUnneeded",
+	"RefuseManualStart", "RefuseManualStop", "AllowIsolate",
+	"SurviveFinalKillSignal", "RemainAfterExit", "GuessMainPID",
+	"RootDirectoryStartOnly", "NonBlocking", "DynamicUser", "PrivateTmp",
+	"PrivateDevices", "PrivateNetwork", "PrivateUsers", "PrivateMounts",
+	"PrivateIPC", "NoNewPrivileges", "ProtectKernelTunables",
+	"ProtectKernelModules", "ProtectKernelLogs", "ProtectControlGroups",
+	"ProtectClock", "ProtectHostname", "RestrictRealtime", "RestrictSUIDSGID",
+	"LockPersonality", "MemoryDenyWriteExecute", "RemoveIPC", "MountAPIVFS",
+	"SendSIGKILL", "SendSIGHUP", "CPUAccounting", "MemoryAccounting",
+	"IOAccounting", "TasksAccounting", "IPAccounting", "Accept", "Writable",
+	"KeepAlive", "NoDelay", "FreeBind", "Transparent", "Broadcast",
+	"PassCredentials", "PassSecurity", "RemoveOnStop", "Persistent",
+	"WakeSystem", "RemainAfterElapse", "OnClockChange", "OnTimezoneChange",
+	"MakeDirectory", "LazyUnmount", "ForceUnmount", "ReadWriteOnly",
+	"SloppyOptions",
+}
+
+// enumDirectives are the settings which take one of the listed values,
+// the ones in booleanEnumDirectives take a boolean as well
+var enumDirectives = map[string][]string{
+	"Type":          {"simple", "exec", "forking", "oneshot", "dbus", "notify", "notify-reload", "idle"},
+	"Restart":       {"no", "on-success", "on-failure", "on-abnormal", "on-watchdog", "on-abort", "always"},
+	"KillMode":      {"control-group", "mixed", "process", "none"},
+	"NotifyAccess":  {"none", "main", "exec", "all"},
+	"ExitType":      {"main", "cgroup"},
+	"OOMPolicy":     {"continue", "stop", "kill"},
+	"ProtectSystem": {"full", "strict"},
+	"ProtectHome":   {"read-only", "tmpfs"},
+	"CollectMode":   {"inactive", "inactive-or-failed"},
+}
+
+// booleanEnumDirectives are the enumDirectives which also take a boolean
+var booleanEnumDirectives = []string{"ProtectSystem", "ProtectHome"}
+
+// dependencyDirectives are the settings which reference other units
+var dependencyDirectives = map[string][]string{
+	"Unit": {
+		"Wants", "Requires", "Requisite", "BindsTo", "PartOf", "Upholds",
+		"Conflicts", "Before", "After", "OnFailure", "OnSuccess",
+		"PropagatesReloadTo", "ReloadPropagatedFrom", "PropagatesStopTo",
+		"StopPropagatedFrom", "JoinsNamespaceOf",
+	},
+	"Install": {"WantedBy", "RequiredBy", "UpheldBy", "Also"},
+	"Socket":  {"Service"},
+	"Timer":   {"Unit"},
+	"Path":    {"Unit"},
+}
+
+// commandDirectives are the settings with a command line
+var commandDirectives = []string{
+	"ExecCondition", "ExecStartPre", "ExecStart", "ExecStartPost", "ExecReload",
+	"ExecStop", "ExecStopPost", "ExecStopPre",
+}
+
+// redirectDirectives are the settings which change the environment,
+// the file system or the search path of the commands, so that another
+// executable than the checked one may run
+var redirectDirectives = []string{
+	"PassEnvironment", "ExecSearchPath", "RootDirectory", "RootImage",
+	"BindPaths", "BindReadOnlyPaths", "TemporaryFileSystem", "MountImages",
+	"ExtensionImages", "ExtensionDirectories",
+}
+
+// requiredDirectives are the settings of which a unit needs at least one
+var requiredDirectives = map[string][]string{
+	"Socket": {
+		"ListenStream", "ListenDatagram", "ListenSequentialPacket", "ListenFIFO",
+		"ListenSpecial", "ListenNetlink", "ListenMessageQueue", "ListenUSBFunction",
+	},
+	"Timer": {
+		"OnActiveSec", "OnBootSec", "OnStartupSec", "OnUnitActiveSec",
+		"OnUnitInactiveSec", "OnCalendar", "OnClockChange", "OnTimezoneChange",
+	},
+	"Path": {
+		"PathExists", "PathExistsGlob", "PathChanged", "PathModified",
+		"DirectoryNotEmpty",
+	},
+	"Mount": {"What"},
+	"Swap":  {"What"},
+}
+
+// checkValue checks the syntax of the value of a known setting
+func checkValue(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	if prop, ok := resourceProperties[key]; ok {
+		var err error
+		switch prop.kind {
+		case kindSize:
+			if strings.HasSuffix(value, "%") {
+				_, err = ParsePercent(value)
+			} else {
+				_, err = ParseSize(value)
+			}
+		case kindPercent:
+			_, err = ParsePercent(value)
+		case kindWeight:
+			_, err = parseWeight(key, value)
+		case kindCount:
+			switch {
+			case value == "infinity":
+			case strings.HasSuffix(value, "%"):
+				_, err = parseScale(value)
+			default:
+				_, err = strconv.ParseUint(value, 10, 64)
+			}
+		case kindTimeSpan:
+			_, err = ParseTimeSpan(value)
+		}
+		return err
+	}
+	if slices.Contains(booleanDirectives, key) {
+		_, err := ParseBoolean(value)
+		return err
+	}
+	if values, ok := enumDirectives[key]; ok {
+		if slices.Contains(booleanEnumDirectives, key) {
+			if _, err := ParseBoolean(value); err == nil {
+				return nil
+			}
+			values = slices.Concat([]string{"yes", "no"}, values)
+		}
+		if !slices.Contains(values, value) {
+			return fmt.Errorf("invalid value %s, valid values are: %v", value, values)
+		}
+		return nil
+	}
+	if strings.HasSuffix(key, "NSec") {
+		_, err := ParseNanoTimeSpan(value)
+		return err
+	}
+	// the device latency has the device as first word
+	if strings.HasSuffix(key, "Sec") && key != "IODeviceLatencyTargetSec" {
+		_, err := ParseTimeSpan(value)
+		return err
+	}
+	return nil
+}
+
+// commandExecutable returns the executable of a command line without the
+// special prefixes like '-' or '+'. Commands with specifiers or variables
+// can't be resolved and return an empty string.
+func commandExecutable(value string) string {
+	value = strings.TrimLeft(value, "@-:+!|")
+	words, err := SplitWords(value)
+	if err != nil || len(words) == 0 {
+		return ""
+	}
+	if strings.ContainsAny(words[0], "%$") {
+		return ""
+	}
+	return words[0]
+}
+
+// checkExecutable checks that the executable of a command exists and is
+// executable, commands without absolute path are looked up in PATH
+func checkExecutable(executable string) error {
+	if !filepath.IsAbs(executable) {
+		_, err := exec.LookPath(executable)
+		if err != nil {
+			return fmt.Errorf("command %s not found in PATH", executable)
+		}
+		return nil
+	}
+	info, err := os.Stat(executable)
+	if err != nil {
+		return fmt.Errorf("command %s doesn't exist", executable)
+	}
+	if info.IsDir() || info.Mode()&0o111 == 0 {
+		return fmt.Errorf("command %s isn't executable", executable)
+	}
+	return nil
+}
+
+// unitExists checks if a unit file for the name is in the search paths, for
+// instances the template is checked as well. Units without file like
+// devices are looked up in the loaded units. The unit isn't loaded by the
+// manager, so verifying has no side effects.
+func (conn *Connection) unitExists(ctx context.Context, name string) bool {
+	names := []string{name}
+	if prefix, suffix, found := strings.Cut(name, "@"); found {
+		if _, ext, ok := strings.Cut(suffix, "."); ok {
+			names = append(names, prefix+"@."+ext)
+		}
+	}
+	for _, dir := range slices.Concat(unitSearchPaths, userSearchPaths()) {
+		for _, n := range names {
+			if _, err := os.Stat(filepath.Join(dir, n)); err == nil {
+				return true
+			}
+		}
+	}
+	// the manager also knows the units of generators and linked files
+	if _, err := conn.dbus.GetUnitFileStateContext(ctx, name); err == nil {
+		return true
+	}
+	units, err := conn.dbus.ListUnitsByPatternsContext(ctx, []string{}, []string{name})
+	if err != nil {
+		// can't tell, so don't report it
+		return true
+	}
+	return slices.ContainsFunc(units, func(unit dbus.UnitStatus) bool {
+		return unit.Name == name && unit.LoadState != "not-found"
+	})
+}
+
+type VerifyUnitParams struct {
+	Name    string `json:"name" jsonschema:"Name of the unit, like foo.service. Used for the unit type and to find the files of an existing unit."`
+	Content string `json:"content,omitempty" jsonschema:"Content of a unit file or drop-in to verify instead of the files of the existing unit."`
+	Path    string `json:"path,omitempty" jsonschema:"Path of a unit file or drop-in in the unit search paths to verify instead of the files of the existing unit."`
+	DropIn  bool   `json:"drop_in,omitempty" jsonschema:"The content or path is a drop-in, so settings required in the unit file aren't checked."`
+}
+
+type VerifyFinding struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Setting  string `json:"setting,omitempty"`
+	Msg      string `json:"message"`
+}
+
+type VerifyResult struct {
+	Unit     string          `json:"unit"`
+	Files    []string        `json:"files"`
+	Valid    bool            `json:"valid"`
+	Findings []VerifyFinding `json:"findings"`
+}
+
+// verifyOptions checks the options of the unit file and its drop-ins, the
+// settings which need the whole unit are checked on the merged options
+func (conn *Connection) verifyOptions(ctx context.Context, name string, opts []UnitOption, dropIn bool) []VerifyFinding {
+	unitType := UnitType(name)
+	findings := []VerifyFinding{}
+	add := func(opt UnitOption, severity, msg string) {
+		findings = append(findings, VerifyFinding{File: opt.File, Line: opt.Line, Severity: severity, Setting: opt.Name, Msg: msg})
+	}
+	checkedUnits := map[string]bool{}
+	for _, opt := range opts {
+		knownSection, knownKey := CheckDirective(unitType, opt.Section, opt.Name)
+		if !knownSection {
+			add(opt, severityError, fmt.Sprintf("unknown section [%s] for %s units, valid sections are: %v", opt.Section, unitType, KnownSections(unitType)))
+			continue
+		}
+		if !knownKey {
+			add(opt, severityWarning, fmt.Sprintf("unknown key %s in section [%s], it's ignored", opt.Name, opt.Section))
+			continue
+		}
+		if err := checkValue(opt.Name, opt.Value); err != nil {
+			add(opt, severityError, err.Error())
+			continue
+		}
+		if slices.Contains(commandDirectives, opt.Name) {
+			if executable := commandExecutable(opt.Value); executable != "" {
+				if err := checkExecutable(executable); err != nil {
+					add(opt, severityError, err.Error())
+				}
+			}
+		}
+		if slices.Contains(dependencyDirectives[opt.Section], opt.Name) {
+			for _, dep := range strings.Fields(opt.Value) {
+				if strings.Contains(dep, "%") || checkedUnits[dep] {
+					continue
+				}
+				checkedUnits[dep] = true
+				if !conn.unitExists(ctx, dep) {
+					add(opt, severityWarning, fmt.Sprintf("referenced unit %s doesn't exist", dep))
+				}
+			}
+		}
+	}
+	settings := MergeUnitOptions(opts)
+	return append(findings, verifySettings(name, settings, dropIn)...)
+}
+
+// verifySettings checks the merged settings for conflicts and for missing
+// required settings
+func verifySettings(name string, settings map[string]map[string][]string, dropIn bool) []VerifyFinding {
+	findings := []VerifyFinding{}
+	add := func(severity, setting, msg string) {
+		findings = append(findings, VerifyFinding{Severity: severity, Setting: setting, Msg: msg})
+	}
+	fields := func(section, key string) []string {
+		ret := []string{}
+		for _, value := range settings[section][key] {
+			ret = append(ret, strings.Fields(value)...)
+		}
+		return ret
+	}
+	last := func(section, key string) string {
+		values := settings[section][key]
+		if len(values) == 0 {
+			return ""
+		}
+		return values[len(values)-1]
+	}
+	conflicts := fields("Unit", "Conflicts")
+	for _, key := range []string{"Requires", "Wants", "BindsTo", "Requisite", "Upholds"} {
+		for _, dep := range fields("Unit", key) {
+			if slices.Contains(conflicts, dep) {
+				add(severityError, key, fmt.Sprintf("%s is in %s= and Conflicts=", dep, key))
+			}
+		}
+	}
+	after := fields("Unit", "After")
+	for _, dep := range fields("Unit", "Before") {
+		if slices.Contains(after, dep) {
+			add(severityError, "Before", fmt.Sprintf("%s is in Before= and After=, which is an ordering cycle", dep))
+		}
+		if dep == name {
+			add(severityError, "Before", "unit is ordered before itself")
+		}
+	}
+	if UnitType(name) == "service" {
+		serviceType := last("Service", "Type")
+		execStart := settings["Service"]["ExecStart"]
+		if serviceType == "oneshot" {
+			if restart := last("Service", "Restart"); restart == "always" || restart == "on-success" {
+				add(severityError, "Restart", fmt.Sprintf("Restart=%s isn't allowed for Type=oneshot services", restart))
+			}
+		} else if len(execStart) > 1 {
+			add(severityError, "ExecStart", "more than one ExecStart= is only allowed for Type=oneshot services")
+		}
+		if !dropIn && len(execStart) == 0 {
+			switch {
+			case len(settings["Service"]["ExecStop"]) == 0 && last("Unit", "SuccessAction") == "":
+				add(severityError, "ExecStart", "service has no ExecStart=, ExecStop= or SuccessAction=")
+			case serviceType != "oneshot":
+				add(severityError, "ExecStart", "service has no ExecStart=, which is only allowed for Type=oneshot services")
+			}
+		}
+		if serviceType == "forking" && last("Service", "PIDFile") == "" {
+			add(severityWarning, "PIDFile", "Type=forking services should set PIDFile=, so the main process can be found")
+		}
+		if last("Service", "DynamicUser") != "" && last("Service", "User") == "root" {
+			if dynamic, _ := ParseBoolean(last("Service", "DynamicUser")); dynamic {
+				add(severityError, "DynamicUser", "DynamicUser= can't be used with User=root")
+			}
+		}
+	}
+	if !dropIn {
+		for section, keys := range requiredDirectives {
+			if _, ok := unitSections[UnitType(name)][section]; !ok {
+				continue
+			}
+			if !slices.ContainsFunc(keys, func(key string) bool { return len(settings[section][key]) > 0 }) {
+				add(severityError, "", fmt.Sprintf("section [%s] needs one of: %s", section, strings.Join(keys, ", ")))
+			}
+		}
+	}
+	return findings
+}
+
+// verify a unit file or drop-in without loading it
+func (conn *Connection) VerifyUnit(ctx context.Context, req *mcp.CallToolRequest, params *VerifyUnitParams) (*mcp.CallToolResult, any, error) {
+	if params.Name == "" {
+		return nil, nil, fmt.Errorf("name of the unit is required")
+	}
+	if KnownSections(UnitType(params.Name)) == nil {
+		return nil, nil, fmt.Errorf("unknown unit type of %s", params.Name)
+	}
+	if params.Content != "" && params.Path != "" {
+		return nil, nil, fmt.Errorf("only one of content and path can be given")
+	}
+	type unitFile struct {
+		path    string
+		content []byte
+	}
+	files := []unitFile{}
+	switch {
+	case params.Content != "":
+		files = append(files, unitFile{content: []byte(params.Content)})
+	case params.Path != "":
+		content, err := readUnitFile(params.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, unitFile{path: params.Path, content: content})
+	default:
+		cat, err := conn.catUnit(ctx, params.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, file := range cat.Files {
+			if file.Error != "" {
+				return nil, nil, fmt.Errorf("couldn't read %s: %s", file.Path, file.Error)
+			}
+			files = append(files, unitFile{path: file.Path, content: []byte(file.Content)})
+		}
+	}
+	result := VerifyResult{
+		Unit:  params.Name,
+		Files: []string{},
+	}
+	opts := []UnitOption{}
+	for _, file := range files {
+		if file.path != "" {
+			result.Files = append(result.Files, file.path)
+		}
+		fileOpts, fileErrs, err := ParseUnitFile(bytes.NewReader(file.content), file.path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, e := range fileErrs {
+			result.Findings = append(result.Findings, VerifyFinding{File: e.File, Line: e.Line, Severity: severityError, Msg: e.Msg})
+		}
+		opts = append(opts, fileOpts...)
+	}
+	dropIn := params.DropIn && (params.Content != "" || params.Path != "")
+	result.Findings = append(result.Findings, conn.verifyOptions(ctx, params.Name, opts, dropIn)...)
+	if result.Findings == nil {
+		result.Findings = []VerifyFinding{}
+	}
+	result.Valid = !slices.ContainsFunc(result.Findings, func(f VerifyFinding) bool { return f.Severity == severityError })
+	jsonByte, err := json.Marshal(result)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(jsonByte),
+			},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,132 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckValue(t *testing.T) {
+	assert.NoError(t, checkValue("TimeoutStartSec", "1min 30s"))
+	assert.Error(t, checkValue("TimeoutStartSec", "5 minutes later"))
+	assert.NoError(t, checkValue("MemoryMax", "50%"))
+	assert.NoError(t, checkValue("MemoryMax", "1G"))
+	assert.Error(t, checkValue("MemoryMax", "1Q"))
+	assert.NoError(t, checkValue("TasksMax", "infinity"))
+	assert.NoError(t, checkValue("TimerSlackNSec", "50ns"))
+	assert.NoError(t, checkValue("TimerSlackNSec", "100"))
+	assert.Error(t, checkValue("TimerSlackNSec", "50 lightyears"))
+	assert.Error(t, checkValue("CPUWeight", "0"))
+	assert.NoError(t, checkValue("CPUWeight", "idle"))
+	assert.Error(t, checkValue("IOWeight", "idle"))
+	assert.NoError(t, checkValue("TasksMax", "50%"))
+	assert.Error(t, checkValue("TasksMax", "150%"))
+	assert.Error(t, checkValue("PrivateTmp", "maybe"))
+	assert.Error(t, checkValue("Type", "daemon"))
+	assert.NoError(t, checkValue("ProtectSystem", "strict"))
+	assert.NoError(t, checkValue("ProtectSystem", "on"))
+	assert.NoError(t, checkValue("ProtectHome", "0"))
+	assert.Error(t, checkValue("ProtectHome", "strict"))
+	assert.NoError(t, checkValue("Description", "anything goes"))
+	assert.NoError(t, checkValue("IODeviceLatencyTargetSec", "/dev/sda 25ms"))
+}
+
+func TestCommandExecutable(t *testing.T) {
+	assert.Equal(t, "/usr/bin/foo", commandExecutable("-/usr/bin/foo --bar"))
+	assert.Equal(t, "/usr/bin/foo", commandExecutable("+@/usr/bin/foo foo"))
+	assert.Equal(t, "", commandExecutable("/usr/lib/%N/run"))
+	assert.Equal(t, "", commandExecutable("${CMD} start"))
+}
+
+func TestVerifyUnit(t *testing.T) {
+	dir := t.TempDir()
+	oldPaths := unitSearchPaths
+	unitSearchPaths = []string{dir}
+	defer func() { unitSearchPaths = oldPaths }()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "network.target"), []byte("[Unit]\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "getty@.service"), []byte("[Unit]\n"), 0o644))
+	executable := filepath.Join(dir, "foo")
+	assert.NoError(t, os.WriteFile(executable, []byte("#!/bin/sh\n"), 0o755))
+	conn := &Connection{
+		dbus: &mockDbusConnection{
+			getAllProperties: func(unitName string) (map[string]interface{}, error) {
+				t.Errorf("referenced unit %s was loaded", unitName)
+				return nil, fmt.Errorf("unexpected call")
+			},
+			getUnitFileState: func(name string) (string, error) {
+				return "", fmt.Errorf("no such file")
+			},
+			listUnitsByPatterns: func(patterns []string, states []string) ([]dbus.UnitStatus, error) {
+				assert.Equal(t, []string{"multi-user.target"}, patterns)
+				return []dbus.UnitStatus{}, nil
+			},
+		},
+	}
+	verify := func(params *VerifyUnitParams) VerifyResult {
+		res, _, err := conn.VerifyUnit(context.Background(), nil, params)
+		assert.NoError(t, err)
+		var result VerifyResult
+		assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(*mcp.TextContent).Text), &result))
+		return result
+	}
+
+	result := verify(&VerifyUnitParams{Name: "foo.service", Content: "[Unit]\nAfter=network.target getty@tty1.service\n[Service]\nExecStart=" + executable + " --run\n[Install]\nWantedBy=multi-user.target\n"})
+	assert.True(t, result.Valid)
+	assert.Equal(t, []VerifyFinding{
+		{Line: 6, Severity: severityWarning, Setting: "WantedBy", Msg: "referenced unit multi-user.target doesn't exist"},
+	}, result.Findings)
+
+	result = verify(&VerifyUnitParams{Name: "foo.service", Content: `[Unit]
+Wants=network.target
+Conflicts=network.target
+Before=network.target
+After=network.target
+[Service]
+Type=oneshot
+Restart=always
+Foo=bar
+TimeoutSec=forever
+ExecStart=/nonexistent/bin
+[Bogus]
+Key=value
+`})
+	assert.False(t, result.Valid)
+	msgs := []string{}
+	for _, finding := range result.Findings {
+		msgs = append(msgs, finding.Msg)
+	}
+	assert.Equal(t, []string{
+		"unknown key Foo in section [Service], it's ignored",
+		"invalid time span: forever",
+		"command /nonexistent/bin doesn't exist",
+		"unknown section [Bogus] for service units, valid sections are: [Unit Install Service]",
+		"network.target is in Wants= and Conflicts=",
+		"network.target is in Before= and After=, which is an ordering cycle",
+		"Restart=always isn't allowed for Type=oneshot services",
+	}, msgs)
+
+	result = verify(&VerifyUnitParams{Name: "foo.service", Content: "[Service]\nMemoryMax=1G\n", DropIn: true})
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Findings)
+
+	result = verify(&VerifyUnitParams{Name: "foo.service", Content: "[Service]\nMemoryMax=1G\n"})
+	assert.False(t, result.Valid)
+	assert.Equal(t, "service has no ExecStart=, ExecStop= or SuccessAction=", result.Findings[0].Msg)
+
+	result = verify(&VerifyUnitParams{Name: "foo.timer", Content: "[Timer]\nAccuracySec=1s\n"})
+	assert.False(t, result.Valid)
+	assert.Equal(t, "section [Timer] needs one of: OnActiveSec, OnBootSec, OnStartupSec, OnUnitActiveSec, OnUnitInactiveSec, OnCalendar, OnClockChange, OnTimezoneChange", result.Findings[0].Msg)
+
+	_, _, err := conn.VerifyUnit(context.Background(), nil, &VerifyUnitParams{Name: "foo.bar", Content: "[Unit]\n"})
+	assert.Error(t, err)
+}
@@ -10,10 +10,17 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/openSUSE/systemd-mcp/internal/pkg/journal"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/login"
+	"github.com/openSUSE/systemd-mcp/internal/pkg/policy"
 	"github.com/openSUSE/systemd-mcp/internal/pkg/systemd"
 )
 
 var httpAddr = flag.String("http", "", "if set, use streamable HTTP at this address, instead of stdin/stdout")
+var allowExec = flag.String("allow-exec", "", "comma separated list of glob patterns of the executables which may be run as transient units, e.g. '/usr/bin/*'")
+var allowPower = flag.String("allow-power", "", "comma separated list of the power actions which may be triggered, e.g. 'reboot,poweroff,cancel' or 'all', cancel allows to cancel a scheduled shutdown")
+var allowUser = flag.String("allow-user", "", "comma separated list of the users which transient units may run as, e.g. 'nobody,backup' or 'all'")
+var allowDirective = flag.String("allow-directive", "", "comma separated list of the settings like 'BindPaths' or 'PassEnvironment' which may be set in drop-ins, although they can change which executables are run")
+var allowSession = flag.String("allow-session", "", "comma separated list of the actions on logind sessions and users which may be done, 'terminate', 'linger' or 'all'")
 
 func main() {
 	flag.Parse()
@@ -23,10 +30,18 @@ func main() {
 		Name:    "Systemd connection",
 		Version: "0.0.1",
 	}, nil)
+	toolPolicy := &policy.Policy{
+		AllowedExecutables:    policy.ParseList(*allowExec),
+		AllowedPowerActions:   policy.ParseList(*allowPower),
+		AllowedSessionActions: policy.ParseList(*allowSession),
+		AllowedUsers:          policy.ParseList(*allowUser),
+		AllowedDirectives:     policy.ParseList(*allowDirective),
+	}
 	systemConn, err := systemd.NewSystem(context.Background())
 	if err != nil {
 		slog.Warn("couldn't add systemd tools", slog.Any("error", err))
 	} else {
+		systemConn.SetPolicy(toolPolicy)
 		// add systend tool handler
 		mcp.AddTool(server, &mcp.Tool{
 			Title:       "List units",
@@ -45,22 +60,160 @@ func main() {
 			Name:        "start_reload_unit",
 			Description: "Start a unit or service. This doesn't enable the unit.",
 		}, systemConn.StartUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "reset_failed",
+			Description: "Reset the failed state and the start rate limit of units, like 'systemctl reset-failed'. Units which hit the start limit (start-limit-hit) can only be started again after this. Without names all failed units are reset.",
+		}, systemConn.ResetFailed)
 		mcp.AddTool(server, &mcp.Tool{
 			Name:        "stop_unit",
 			Description: "Stop a unit or service or unit.",
 		}, systemConn.StopUnit)
 		mcp.AddTool(server, &mcp.Tool{
 			Name:        "check_restart_reload",
-			Description: "Check the reload or restart status of a unit. Can only be called if the restart or reload job had a timeout.",
+			Description: "Get the result of a start, stop, reload or restart job which was still in progress when its tool returned. Waits for the next job to finish and returns the unit and result of every finished job and the units of the jobs still in progress, or that no job is in progress.",
 		}, systemConn.CheckForRestartReloadRunning)
 		mcp.AddTool(server, &mcp.Tool{
 			Name:        "enable_or_disable_unit",
-			Description: "Enable an unit or service for the next startup of the system. This doesn't start the unit.",
+			Description: "Enable or disable an unit or service for the next startup of the system. With 'now' the unit is also started or stopped in the same call, with 'runtime' the change only lasts until the next reboot.",
 		}, systemConn.EnableDisableUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "manage_unit_file",
+			Description: "Mask, unmask, link or preset unit files. A masked unit can't be started, not even manually or as dependency. Link makes a unit file outside of the search paths known to systemd. Preset enables or disables units according to the preset policy of the distribution. Returns the changed symlinks.",
+		}, systemConn.ManageUnitFile)
 		mcp.AddTool(server, &mcp.Tool{
 			Name:        "list_unit_files",
 			Description: "Returns a list of all the unit files known to systemd. This tool can be used to determine the correct names for all the other correct unit/service names for the other calls.",
 		}, systemConn.ListUnitFiles)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "list_unit_dependencies",
+			Description: "List the dependencies (Requires, Wants, BindsTo, PartOf, After, Before, Conflicts) of a unit recursively, like 'systemctl list-dependencies'. With reverse the units depending on the given unit are listed, which shows the impact of stopping the unit. Returns the nodes and edges of the graph as json or a Graphviz dot graph.",
+		}, systemConn.ListDependencies)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "diagnose_unit",
+			Description: "Diagnose a failed or misbehaving unit with a single call. Returns the result, the decoded exit code or signal of the main process, the number of restarts, the start limit state, failed conditions and asserts, the last error log lines of the latest run and the last coredump as json.",
+		}, systemConn.DiagnoseUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "cat_unit",
+			Description: "Show the unit file and its drop-ins in the order they are applied, like 'systemctl cat'. Returns the path and content of every file, the effective settings per section after merging the drop-ins and syntax errors as json. Only files in the unit search paths are read.",
+		}, systemConn.CatUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "edit_unit_override",
+			Description: "Write or replace a drop-in override for a unit, like 'systemctl edit'. The sections and keys are checked against the known settings of the unit type, the previous content is kept as backup and daemon-reload is run afterwards. Returns the path of the drop-in, the backup and a unified diff of the change.",
+		}, systemConn.EditOverride)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "revert_unit_overrides",
+			Description: "Remove the drop-in overrides of a unit in /etc and /run, like 'systemctl revert', and run daemon-reload. A backup of every removed drop-in is kept. Returns the removed files and their diffs.",
+		}, systemConn.RevertOverride)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "set_unit_properties",
+			Description: fmt.Sprintf("Change resource control settings of a unit at runtime, like 'systemctl set-property', e.g. to throttle a runaway service. The values are validated per property. Valid properties are: %v", systemd.ValidResourceProperties()),
+		}, systemConn.SetUnitProperties)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "run_transient_unit",
+			Description: "Run a command as transient service or scope, like 'systemd-run'. Supports user, working directory, environment, resource limits, RuntimeMaxSec and a transient timer for delayed or scheduled runs. Returns the unit name and job result, with wait also the exit status and the output of the command. Only executables allowed by the policy of the server can be run.",
+		}, systemConn.RunTransient)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "list_timers",
+			Description: "List the timers sorted by their next elapse, like 'systemctl list-timers'. Returns the next elapse as wall clock time with the time left, the last trigger, the activated unit and its last result as json.",
+		}, systemConn.ListTimers)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "list_sockets",
+			Description: "List the socket units with their listen addresses, the services they activate, the number of current, accepted and refused connections and their state, like 'systemctl list-sockets'. With service only the sockets activating the given service are listed, which helps to debug a port which isn't listening.",
+		}, systemConn.ListSockets)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "list_mounts",
+			Description: "List the mount, automount and swap units with what is mounted where, the file system type, options, state and the units requiring them. The state is cross-checked with the mounts and swaps of the kernel and failed mounts created by the fstab generator are pointed out. Use failed to only list the problematic units.",
+		}, systemConn.ListMounts)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "analyze_boot",
+			Description: "Analyze the boot performance like 'systemd-analyze time', 'blame' and 'critical-chain'. Returns the durations of firmware, loader, kernel, initrd and userspace, the units which took the longest to start and the chain of units which delayed the given target as json.",
+		}, systemConn.AnalyzeBoot)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "security_audit",
+			Description: "Audit the sandboxing of a service or of all running services like 'systemd-analyze security'. Returns the exposure level from 0 (sandboxed) to 10 (unprotected), the weak settings with the remediation and the risk of each as json. Apply the remediations one at a time and test the service, the drop-in which combines them is only a starting point.",
+		}, systemConn.SecurityAudit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "verify_unit",
+			Description: "Verify a unit file like 'systemd-analyze verify' without loading it. Checks the files of an existing unit or the given content, like a generated unit or a drop-in, before it's written. Reports unknown sections and keys, invalid values, missing executables, references to non-existent units and conflicting settings as json.",
+		}, systemConn.VerifyUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "unit_resources",
+			Description: fmt.Sprintf("Show the resource usage of a unit from its cgroup, like memory, CPU, IO, tasks and pressure stall information, with the CPU usage in percent and the IO rate sampled over a short interval. Without name the services using the most resources are listed like 'systemd-cgtop', sorted by one of %v.", systemd.ValidResourceSorts()),
+		}, systemConn.UnitResources)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "unit_processes",
+			Description: "List the processes in the cgroup of a unit with PID, parent, command line, user, RSS, state and start time, and show them as tree like 'systemctl status'. Zombies and processes which outlived the main process are flagged. Use it to understand a hung service before killing it.",
+		}, systemConn.UnitProcesses)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "kill_unit",
+			Description: fmt.Sprintf("Send a signal like SIGTERM, SIGHUP or SIGUSR1 to the processes of a unit, like 'systemctl kill'. The processes to signal are one of %v. Optionally the remaining processes get SIGKILL after waiting the given seconds. Returns the PIDs which received the signals as json.", systemd.ValidKillTargets()),
+		}, systemConn.KillUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "freeze_unit",
+			Description: "Freeze all processes of an active unit with the cgroup freezer, like 'systemctl freeze'. The processes are suspended but keep their state, which is safer than stopping a misbehaving service while its state is captured. Needs the unified cgroup hierarchy.",
+		}, systemConn.FreezeUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "thaw_unit",
+			Description: "Resume the processes of a unit frozen with freeze_unit, like 'systemctl thaw'.",
+		}, systemConn.ThawUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "clean_unit",
+			Description: fmt.Sprintf("Remove the directories of a stopped unit, like 'systemctl clean'. The kinds of directories are %v. Without confirm the directories which would be removed are listed with their sizes, call again with confirm to remove them.", systemd.ValidCleanKinds()),
+		}, systemConn.CleanUnit)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "daemon_reload",
+			Description: "Reload the unit files of the manager after they were changed, like 'systemctl daemon-reload'. With reexec the manager is executed again, like 'systemctl daemon-reexec'.",
+		}, systemConn.DaemonReload)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "system_state",
+			Description: "Show the state of the manager like 'systemctl is-system-running', e.g. running or degraded, with the version, the default target and the failed units as json.",
+		}, systemConn.SystemState)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "default_target",
+			Description: "Get the default target the system boots into, or set it like 'systemctl set-default'. Setting it is a high risk action, without confirm only the change is described.",
+		}, systemConn.DefaultTarget)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "isolate_target",
+			Description: "Switch to a target like rescue.target and stop all units not needed by it, like 'systemctl isolate'. This is a high risk action, without confirm only the active targets are listed.",
+		}, systemConn.IsolateTarget)
+	}
+	loginConn, err := login.New(context.Background())
+	if err != nil {
+		slog.Warn("couldn't connect to logind, not adding power and session tools", slog.Any("error", err))
+	} else {
+		loginConn.SetPolicy(toolPolicy)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "power_status",
+			Description: "Show which power actions like reboot or suspend are possible, a scheduled shutdown and the inhibitors which delay or block shutdown and sleep as json.",
+		}, loginConn.PowerStatus)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "power_action",
+			Description: fmt.Sprintf("Trigger a power action now or schedule a reboot or poweroff with a delay, or cancel the scheduled shutdown. Refuses if a block inhibitor is held unless the inhibitors are ignored explicitly. Only actions allowed by the policy of the server can be triggered. Valid actions are: %v", login.ValidPowerActions()),
+		}, loginConn.PowerAction)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "list_sessions",
+			Description: "List the login sessions like 'loginctl list-sessions' with user, seat, TTY, remote host, class, state and idle state as json.",
+		}, loginConn.ListSessions)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "list_login_users",
+			Description: "List the logged in and lingering users like 'loginctl list-users' with their sessions and whether linger is enabled, which keeps their user manager and user services running without a session.",
+		}, loginConn.ListLoginUsers)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "list_seats",
+			Description: "List the seats like 'loginctl list-seats' with their active session.",
+		}, loginConn.ListSeats)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "list_inhibitors",
+			Description: "List the inhibitor locks which delay or block shutdown, sleep, idle or the handling of keys like 'systemd-inhibit --list'.",
+		}, loginConn.ListInhibitors)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "terminate_session",
+			Description: "Terminate a login session and kill all its processes like 'loginctl terminate-session'. Only possible if allowed by the policy of the server.",
+		}, loginConn.TerminateSession)
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "set_user_linger",
+			Description: "Enable or disable linger of a user like 'loginctl enable-linger', so the user services keep running without a session. Only possible if allowed by the policy of the server.",
+		}, loginConn.SetUserLinger)
 	}
 	descriptionJournal := "Get the last log entries for the given service or unit."
 	if os.Geteuid() != 0 {
@@ -70,6 +223,10 @@ func main() {
 	if err != nil {
 		slog.Warn("couldn't open log, not adding journal tool", slog.Any("error", err))
 	} else {
+		if systemConn != nil {
+			log.SetUnitProperties(systemConn)
+			systemConn.SetUnitLog(log)
+		}
 		mcp.AddTool(server, &mcp.Tool{
 			Name:        "list_log",
 			Description: descriptionJournal,
@@ -88,4 +245,7 @@ func main() {
 		}
 	}
 	systemConn.Close()
+	if loginConn != nil {
+		loginConn.Close()
+	}
 }